@@ -1,7 +1,8 @@
 package auth
 
 import (
-	"fmt"
+	"errors"
+	"reflect"
 	"strings"
 	"time"
 
@@ -10,8 +11,58 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// FieldError describes one failed validation rule of a request body, so
+// the dashboard can highlight the offending field.
+type FieldError struct {
+	Field string `json:"field"` // JSON name of the field, e.g. "username"
+	Rule  string `json:"rule"`  // validator tag that failed, e.g. "required"
+}
+
+// loginValidator reports fields by their JSON names.
+var loginValidator = func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}()
+
+// fieldErrors converts a validation error into FieldErrors.
+func fieldErrors(err error) []FieldError {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []FieldError{}
+	}
+	out := make([]FieldError, 0, len(verrs))
+	for _, e := range verrs {
+		out = append(out, FieldError{Field: e.Field(), Rule: e.Tag()})
+	}
+	return out
+}
+
+// Credential is an additional username/password accepted by
+// LoginHandler, with the role its tokens carry (e.g. RoleViewer).
+type Credential struct {
+	Username string
+	Password string
+	Role     string
+}
+
 // LoginHandler returns a Fiber handler for POST /api/monitoring/authentication/login.
-func LoginHandler(username, password, jwtSecret string) fiber.Handler {
+// username/password log in as RoleAdmin; extra credentials with an empty
+// username or password are ignored.
+func LoginHandler(username, password, jwtSecret string, extra ...Credential) fiber.Handler {
+	creds := []Credential{{Username: username, Password: password, Role: RoleAdmin}}
+	for _, cr := range extra {
+		if cr.Username != "" && cr.Password != "" {
+			creds = append(creds, cr)
+		}
+	}
+
 	return func(c *fiber.Ctx) error {
 		var body struct {
 			Username string `json:"username" validate:"required"`
@@ -25,17 +76,23 @@ func LoginHandler(username, password, jwtSecret string) fiber.Handler {
 			})
 		}
 
-		var validate = validator.New()
-		if err := validate.Struct(body); err != nil {
-			// Collect error messages
-			var messages []string
-			for _, err := range err.(validator.ValidationErrors) {
-				messages = append(messages, fmt.Sprintf("%s is %s", err.Field(), err.Tag()))
-			}
-			return fiber.NewError(fiber.StatusBadRequest, strings.Join(messages, ", "))
+		if err := loginValidator.Struct(body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "validation failed",
+				"success":    false,
+				"errors":     fieldErrors(err),
+			})
 		}
 
-		if body.Username != username || body.Password != password {
+		role, ok := "", false
+		for _, cr := range creds {
+			if body.Username == cr.Username && body.Password == cr.Password {
+				role, ok = cr.Role, true
+				break
+			}
+		}
+		if !ok {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"statusCode": fiber.StatusBadRequest,
 				"message":    "Wrong Credentials",
@@ -44,8 +101,9 @@ func LoginHandler(username, password, jwtSecret string) fiber.Handler {
 		}
 
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"id":  body.Username + "-" + time.Now().Format(time.RFC3339),
-			"exp": time.Now().Add(10 * time.Hour).Unix(),
+			"id":   body.Username + "-" + time.Now().Format(time.RFC3339),
+			"role": role,
+			"exp":  time.Now().Add(10 * time.Hour).Unix(),
 		})
 		signed, err := token.SignedString([]byte(jwtSecret))
 		if err != nil {
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksTTL is how long fetched keys are trusted before a refresh.
+	jwksTTL = time.Hour
+	// jwksMinRefresh limits refetches triggered by unknown key IDs, so
+	// tokens with bogus kids cannot hammer the identity provider.
+	jwksMinRefresh = time.Minute
+)
+
+// JWKSValidator validates Bearer tokens signed with RS256 by an external
+// identity provider, using the public keys published at jwksURL (e.g.
+// https://idp.example.com/.well-known/jwks.json). Keys are cached for an
+// hour and refreshed early when a token names an unknown key ID.
+//
+// Tokens must carry the given iss and aud claims, so tokens the provider
+// issued to other applications are refused. Both are required; without
+// them every token is rejected.
+func JWKSValidator(jwksURL, issuer, audience string) Validator {
+	if issuer == "" || audience == "" {
+		log.Println("[go-monitoring] error: JWKS authentication needs an issuer and an audience; rejecting all tokens")
+		return func(*fiber.Ctx) (map[string]any, bool) { return nil, false }
+	}
+	keys := &jwksCache{
+		url:    jwksURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	return func(c *fiber.Ctx) (map[string]any, bool) {
+		raw, ok := bearerToken(c)
+		if !ok {
+			return nil, false
+		}
+
+		token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return keys.key(kid)
+		},
+			jwt.WithValidMethods([]string{"RS256"}),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+		)
+		if err != nil || !token.Valid {
+			return nil, false
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, false
+		}
+		return claims, true
+	}
+}
+
+// jwksCache fetches and caches the RSA keys of a JWKS endpoint.
+type jwksCache struct {
+	url    string
+	client *http.Client
+
+	// mu guards keys and fetchedAt and is never held during the HTTP
+	// request; fetchMu lets a single caller refetch at a time.
+	mu        sync.Mutex
+	fetchMu   sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// key returns the public key for kid, fetching the key set when it is
+// stale or does not contain kid. An empty kid matches a lone key.
+func (j *jwksCache) key(kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	k, ok := j.lookup(kid)
+	fresh := time.Since(j.fetchedAt) < jwksTTL
+	j.mu.Unlock()
+	if ok && fresh {
+		return k, nil
+	}
+
+	j.refresh()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if k, ok := j.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("auth: no JWKS key for kid %q", kid)
+}
+
+// refresh refetches the key set unless that happened within
+// jwksMinRefresh. Callers waiting on fetchMu see the result of the
+// fetch in progress instead of starting another.
+func (j *jwksCache) refresh() {
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	j.mu.Lock()
+	if j.keys != nil && time.Since(j.fetchedAt) < jwksMinRefresh {
+		j.mu.Unlock()
+		return
+	}
+	// Record the attempt even on failure to respect jwksMinRefresh.
+	j.fetchedAt = time.Now()
+	j.mu.Unlock()
+
+	keys, err := j.fetch()
+	if err != nil {
+		log.Printf("[go-monitoring] error fetching JWKS from %s: %v\n", j.url, err)
+		return
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+}
+
+func (j *jwksCache) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, k := range j.keys {
+			return k, true
+		}
+	}
+	k, ok := j.keys[kid]
+	return k, ok
+}
+
+// fetch downloads the current key set.
+func (j *jwksCache) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := rsaPublicKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable RSA signing keys")
+	}
+	return keys, nil
+}
+
+// rsaPublicKey decodes the base64url modulus and exponent of a JWK.
+func rsaPublicKey(n, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(eb)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(exp.Int64())}, nil
+}
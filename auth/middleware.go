@@ -7,10 +7,27 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Validator authenticates a request, returning the caller's claims when
+// it is allowed through. It lets the dashboard reuse an existing
+// session or SSO check instead of the built-in JWT login.
+type Validator func(c *fiber.Ctx) (claims map[string]any, ok bool)
+
 // Guard returns a Fiber middleware that validates a Bearer JWT token.
 // When authRequired is false the guard is a no-op.
 // When apisEnabled is false every request gets a 404.
-func Guard(authRequired, apisEnabled bool, jwtSecret string) fiber.Handler {
+func Guard(authRequired, apisEnabled bool, jwtSecret string, roles ...RoleMapping) fiber.Handler {
+	return GuardWith(authRequired, apisEnabled, JWTValidator(jwtSecret), roles...)
+}
+
+// GuardWith is like Guard but delegates authentication to validate.
+// The returned claims are stored in c.Locals("monitoring_user") and the
+// role derived from them (see RoleMapping) in c.Locals(RoleLocalsKey).
+func GuardWith(authRequired, apisEnabled bool, validate Validator, roles ...RoleMapping) fiber.Handler {
+	var mapping RoleMapping
+	if len(roles) > 0 {
+		mapping = roles[0]
+	}
+
 	return func(c *fiber.Ctx) error {
 		if !apisEnabled {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -23,9 +40,8 @@ func Guard(authRequired, apisEnabled bool, jwtSecret string) fiber.Handler {
 			return c.Next()
 		}
 
-		authHeader := c.Get("Authorization")
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		claims, ok := validate(c)
+		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"statusCode": fiber.StatusUnauthorized,
 				"message":    "unauthorized",
@@ -33,30 +49,45 @@ func Guard(authRequired, apisEnabled bool, jwtSecret string) fiber.Handler {
 			})
 		}
 
-		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
+		c.Locals("monitoring_user", jwt.MapClaims(claims))
+		c.Locals(RoleLocalsKey, mapping.role(claims))
+		return c.Next()
+	}
+}
+
+// JWTValidator validates a Bearer JWT signed with the HMAC jwtSecret, as
+// issued by LoginHandler.
+func JWTValidator(jwtSecret string) Validator {
+	return func(c *fiber.Ctx) (map[string]any, bool) {
+		raw, ok := bearerToken(c)
+		if !ok {
+			return nil, false
+		}
+
+		token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
 			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, jwt.ErrSignatureInvalid
 			}
 			return []byte(jwtSecret), nil
 		})
 		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"statusCode": fiber.StatusUnauthorized,
-				"message":    "unauthorized",
-				"success":    false,
-			})
+			return nil, false
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"statusCode": fiber.StatusUnauthorized,
-				"message":    "unauthorized",
-				"success":    false,
-			})
+			return nil, false
 		}
+		return claims, true
+	}
+}
 
-		c.Locals("monitoring_user", claims)
-		return c.Next()
+// bearerToken extracts the token from an "Authorization: Bearer <token>"
+// header.
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	parts := strings.SplitN(c.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
 	}
+	return parts[1], true
 }
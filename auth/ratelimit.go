@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitStore tracks attempt counters per key within a fixed window.
+// Implement it with a shared backend (e.g. Redis) when running multiple
+// instances so the limit is enforced across the whole deployment.
+type RateLimitStore interface {
+	// Hit records an attempt for key and returns the number of attempts
+	// made in the current window together with the time the window resets.
+	Hit(key string, window time.Duration) (count int, resetAt time.Time)
+}
+
+// MemoryRateLimitStore is an in-process fixed-window RateLimitStore.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*rateLimitEntry
+}
+
+type rateLimitEntry struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{entries: make(map[string]*rateLimitEntry)}
+}
+
+// Hit implements RateLimitStore.
+func (s *MemoryRateLimitStore) Hit(key string, window time.Duration) (int, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		// Expired entries are replaced lazily; sweep the rest so the map
+		// does not grow without bound under many distinct clients.
+		s.sweep(now)
+		e = &rateLimitEntry{resetAt: now.Add(window)}
+		s.entries[key] = e
+	}
+	e.count++
+	return e.count, e.resetAt
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for k, e := range s.entries {
+		if !now.Before(e.resetAt) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+// RateLimit returns a Fiber middleware that allows at most limit requests
+// per client IP within window. When limit <= 0 the middleware is a no-op.
+// A nil store falls back to a MemoryRateLimitStore.
+func RateLimit(limit int, window time.Duration, store RateLimitStore) fiber.Handler {
+	return rateLimit(limit, window, store, func(c *fiber.Ctx) string { return c.IP() },
+		"too many login attempts, try again later")
+}
+
+// APIRateLimit limits the analytics API to limit requests per window for
+// each client, identified by its Bearer token once Guard has validated
+// it, or else by its IP. Mount it after Guard. It behaves like RateLimit
+// otherwise; use a separate store from the login limiter (or a shared
+// one: keys are prefixed).
+func APIRateLimit(limit int, window time.Duration, store RateLimitStore) fiber.Handler {
+	return rateLimit(limit, window, store, apiClientKey,
+		"too many requests to the monitoring API, try again later")
+}
+
+// apiClientKey identifies an API client by a hash of its token, so raw
+// tokens are never kept in the store. Unvalidated tokens (no auth, or a
+// guard that did not run) fall back to the IP: otherwise a client could
+// dodge the limit, and grow the store, by sending a new token each time.
+func apiClientKey(c *fiber.Ctx) string {
+	if token, ok := bearerToken(c); ok && c.Locals("monitoring_user") != nil {
+		sum := sha256.Sum256([]byte(token))
+		return "api:token:" + hex.EncodeToString(sum[:16])
+	}
+	return "api:ip:" + c.IP()
+}
+
+func rateLimit(limit int, window time.Duration, store RateLimitStore, key func(*fiber.Ctx) string, message string) fiber.Handler {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	return func(c *fiber.Ctx) error {
+		if limit <= 0 {
+			return c.Next()
+		}
+
+		count, resetAt := store.Hit(key(c), window)
+		if count > limit {
+			retryAfter := int(time.Until(resetAt).Seconds()) + 1
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"statusCode": fiber.StatusTooManyRequests,
+				"message":    message,
+				"success":    false,
+				"retryAfter": retryAfter,
+			})
+		}
+		return c.Next()
+	}
+}
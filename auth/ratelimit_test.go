@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAPIRateLimitKeysByValidatedToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		authRequired bool
+		want         int // status of the third request, each with a new token
+	}{
+		{"no auth limits by IP", false, fiber.StatusTooManyRequests},
+		{"validated tokens get own buckets", true, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryRateLimitStore()
+			accept := func(*fiber.Ctx) (map[string]any, bool) { return map[string]any{}, true }
+			app := fiber.New()
+			app.Get("/api", GuardWith(tt.authRequired, true, accept),
+				APIRateLimit(2, time.Minute, store),
+				func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+			var status int
+			for i := range 3 {
+				req := httptest.NewRequest("GET", "/api", nil)
+				req.Header.Set("Authorization", "Bearer token-"+strconv.Itoa(i))
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				status = resp.StatusCode
+			}
+			if status != tt.want {
+				t.Errorf("third request status = %d, want %d", status, tt.want)
+			}
+			if !tt.authRequired && len(store.entries) != 1 {
+				t.Errorf("store holds %d keys, want 1", len(store.entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+// Built-in roles. Viewers may read analytics; admins may also run
+// destructive operations such as DELETE /clear.
+const (
+	RoleAdmin  = "admin"
+	RoleViewer = "viewer"
+)
+
+// RoleLocalsKey is the c.Locals key under which Guard stores the
+// caller's role.
+const RoleLocalsKey = "monitoring_role"
+
+// RoleMapping derives the caller's role from the claims returned by the
+// guard's Validator.
+type RoleMapping struct {
+	// Claim names the claim holding the role (default: "role"). It may be
+	// a string or a list of strings, e.g. IdP groups.
+	Claim string
+
+	// Map translates claim values to roles, e.g. {"ops": "admin"}.
+	// Values that neither map to a built-in role nor are one are ignored.
+	Map map[string]string
+
+	// Default is the role of callers whose claims carry no recognised
+	// role (default: RoleViewer).
+	Default string
+}
+
+// role returns the role for claims. It fails closed: only values that
+// resolve to RoleAdmin or RoleViewer count, and when a list names both,
+// admin wins.
+func (m RoleMapping) role(claims map[string]any) string {
+	claim := m.Claim
+	if claim == "" {
+		claim = "role"
+	}
+	var values []string
+	switch v := claims[claim].(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	found := ""
+	for _, v := range values {
+		r, ok := m.Map[v]
+		if !ok {
+			r = v
+		}
+		switch r {
+		case RoleAdmin:
+			return RoleAdmin
+		case RoleViewer:
+			found = RoleViewer
+		}
+	}
+	if found != "" {
+		return found
+	}
+	if isRole(m.Default) {
+		return m.Default
+	}
+	return RoleViewer
+}
+
+// isRole reports whether r is a built-in role.
+func isRole(r string) bool {
+	return r == RoleAdmin || r == RoleViewer
+}
+
+// RequireRole returns a middleware, mounted after Guard, that responds
+// 403 unless the caller has one of roles. It is a no-op when Guard did
+// not authenticate the request because authentication is disabled.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, ok := c.Locals(RoleLocalsKey).(string)
+		if !ok {
+			return c.Next()
+		}
+		for _, r := range roles {
+			if role == r {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "forbidden",
+			"success":    false,
+		})
+	}
+}
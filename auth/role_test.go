@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRoleMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping RoleMapping
+		claims  map[string]any
+		want    string
+	}{
+		{"missing claim", RoleMapping{}, map[string]any{}, RoleViewer},
+		{"admin", RoleMapping{}, map[string]any{"role": "admin"}, RoleAdmin},
+		{"viewer", RoleMapping{}, map[string]any{"role": "viewer"}, RoleViewer},
+		{"unknown value", RoleMapping{}, map[string]any{"role": "superuser"}, RoleViewer},
+		{"unknown value falls back to default", RoleMapping{Default: RoleAdmin}, map[string]any{"role": "superuser"}, RoleAdmin},
+		{"invalid default", RoleMapping{Default: "root"}, map[string]any{}, RoleViewer},
+		{"mapped", RoleMapping{Map: map[string]string{"ops": RoleAdmin}}, map[string]any{"role": "ops"}, RoleAdmin},
+		{"mapped to unknown", RoleMapping{Map: map[string]string{"ops": "root"}}, map[string]any{"role": "ops"}, RoleViewer},
+		{"custom claim", RoleMapping{Claim: "groups"}, map[string]any{"groups": "admin", "role": "viewer"}, RoleAdmin},
+		{"wrong type", RoleMapping{}, map[string]any{"role": 1}, RoleViewer},
+		{
+			"list prefers admin",
+			RoleMapping{Claim: "groups", Map: map[string]string{"ops": RoleAdmin, "staff": RoleViewer}},
+			map[string]any{"groups": []any{"staff", "everyone", "ops"}},
+			RoleAdmin,
+		},
+		{
+			"list without known role",
+			RoleMapping{Claim: "groups"},
+			map[string]any{"groups": []any{"everyone", 3}},
+			RoleViewer,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.role(tt.claims); got != tt.want {
+				t.Errorf("role() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name string
+		role any // nil leaves the locals unset
+		want int
+	}{
+		{"admin", RoleAdmin, fiber.StatusOK},
+		{"viewer", RoleViewer, fiber.StatusForbidden},
+		{"unauthenticated", nil, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Delete("/clear", func(c *fiber.Ctx) error {
+				if tt.role != nil {
+					c.Locals(RoleLocalsKey, tt.role)
+				}
+				return c.Next()
+			}, RequireRole(RoleAdmin), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("DELETE", "/clear", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,14 @@ package monitoring
 
 import (
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/aghiadodeh/go-monitoring/auth"
+	"github.com/aghiadodeh/go-monitoring/logwriter"
+	"github.com/gofiber/fiber/v2"
 )
 
 // Config holds all monitoring configuration loaded from environment variables.
@@ -11,53 +17,313 @@ type Config struct {
 	// Request logging
 	RequestSaveEnabled bool
 
+	// Storage
+	TablePrefix   string // prefix (or "schema.") for monitoring table names (default: none)
+	UTCTimestamps bool   // store created_at/updated_at in UTC instead of local time (default: false)
+
 	// Dashboard
 	DashboardEnabled bool
 	DashboardPath    string // optional filesystem path override (empty = use embedded assets)
+	DashboardCORS    CORSConfig
+	DashboardRoute   string // URL prefix the dashboard is served under (default: "/monitoring")
+	APIBasePath      string // URL prefix of the monitoring API (default: "/api/monitoring")
 
 	// Authentication
-	AuthRequired bool
-	APIsEnabled  bool
-	Username     string
-	Password     string
-	JWTSecret    string
+	AuthRequired  bool
+	APIsEnabled   bool
+	HealthGuarded bool // require auth for GET /api/monitoring/health (default: false)
+	Username      string
+	Password      string
+	JWTSecret     string
+
+	// ViewerUsername and ViewerPassword enable a second login whose
+	// tokens carry the viewer role: read access to analytics, but no
+	// DELETE /clear. Disabled unless both are set.
+	ViewerUsername string
+	ViewerPassword string
+
+	// AuthValidator replaces the built-in JWT check with your own session
+	// or SSO validation. Setting it enables the guard even when
+	// AuthRequired is false.
+	AuthValidator func(*fiber.Ctx) (claims map[string]any, ok bool)
+
+	// JWKSURL verifies RS256 tokens from your identity provider against
+	// the keys published at this URL, instead of JWTSecret. Like
+	// AuthValidator (which takes precedence), it enables the guard.
+	JWKSURL string
+
+	// JWKSIssuer and JWKSAudience are the iss and aud claims JWKS tokens
+	// must carry, so tokens the provider issued to other applications are
+	// refused. Both are required with JWKSURL.
+	JWKSIssuer   string
+	JWKSAudience string
+
+	// Roles. The guard reads the caller's role from the RoleClaim claim
+	// (default: "role"), translating values through RoleMap, e.g.
+	// {"ops-admins": "admin"}. Callers without a recognised role get
+	// DefaultRole (default: "viewer"). DELETE /clear requires the admin
+	// role; the read endpoints accept any authenticated caller.
+	RoleClaim   string
+	RoleMap     map[string]string
+	DefaultRole string
+
+	// Login brute-force protection
+	LoginRateLimit  int                 // max login attempts per IP per window (default: 5, 0 = disabled)
+	LoginRateWindow time.Duration       // window for LoginRateLimit (default: 1m)
+	LoginRateStore  auth.RateLimitStore // attempt counter backend (default: in-memory)
+
+	// Analytics API protection (per Bearer token, or per IP without one)
+	AnalyticsRateLimit  int                 // max protected API requests per client per window (default: 0 = disabled)
+	AnalyticsRateWindow time.Duration       // window for AnalyticsRateLimit (default: 1m)
+	AnalyticsRateStore  auth.RateLimitStore // request counter backend (default: in-memory)
 
 	// Log writer performance tuning
 	BufferSize    int           // channel buffer size (default: 10000)
 	BatchSize     int           // records per batch insert (default: 100)
 	FlushInterval time.Duration // max time between flushes (default: 5s)
 	Workers       int           // number of writer goroutines (default: 1)
+	MaxBatchBytes int           // flush early once a batch reaches ~N bytes (default: 0 = unlimited)
+	MaxDBConns    int           // max workers inserting at once; 1 = serialized flushes (default: 0 = Workers)
+	DedupWrites   bool          // drop logs of the same request written in the last minute (default: false)
+	RecentWindow  time.Duration // span covered by GET /now (default: 60s)
+
+	OnConflictDoNothing bool // skip rows whose ID already exists instead of failing the batch (default: false)
+	InsertChunkSize     int  // rows per INSERT; a failed chunk is retried row by row (default: 50)
+
+	OverflowPolicy logwriter.OverflowPolicy // full buffer behaviour: "drop", "block" or "drop_oldest" (default: drop)
+	BlockTimeout   time.Duration            // max wait when OverflowPolicy is "block" (default: 100ms)
+
+	ShutdownTimeout time.Duration // max time to flush on app shutdown (default: 10s)
+	HandleSignals   bool          // shut the app down gracefully on SIGINT/SIGTERM, flushing logs, for apps that never call app.Shutdown (default: false)
+
+	FallbackCapacity int // failed batches kept in memory for retry (default: 0 = disabled)
+
+	// OpenTelemetry span export
+	OTLPEndpoint    string // OTLP/HTTP traces URL, e.g. http://collector:4318/v1/traces (empty = disabled)
+	OTLPServiceName string // service.name reported with each span (default: "go-monitoring")
 
 	// Middleware options
 	SkipPaths       []string // URL prefixes to skip logging (default: ["/api/monitoring"])
 	UserContextKey  string   // key for user data in c.Locals() (default: "user")
 	MaxBodySize     int      // max request/response body bytes to capture (default: 64KB, -1 = unlimited)
+	MaxReqBodySize  int      // request body cap (default: 0 = use MaxBodySize, -1 = unlimited)
+	MaxRespBodySize int      // response body cap (default: 0 = use MaxBodySize, -1 = unlimited)
 	CaptureReqBody  bool     // capture request body (default: true)
 	CaptureRespBody bool     // capture response body (default: true)
+	TraceHeader     string   // request header carrying the correlation ID (default: X-Request-Id, then traceparent)
+
+	SkipBodyContentTypes []string // content types whose bodies are not captured (default: multipart, octet-stream, image/*)
+	ExposeBufferHeader   bool     // add X-Monitoring-Buffer: used/cap to monitored responses (default: false)
+
+	CaptureReqBodyOnErrorOnly  bool // keep request bodies only for failed requests (default: false)
+	CaptureRespBodyOnErrorOnly bool // keep response bodies only for failed requests (default: false)
+
+	CaptureBodyOverDurationMs float64 // keep bodies only for requests slower than N ms; or failed, with *OnErrorOnly (default: 0 = off)
+
+	IndexReqBodyFields []string // top-level JSON request body keys stored in indexed_fields for ?field= search (default: none)
+
+	SkipHeader  string // request header that skips logging when "1"/"true", e.g. X-Monitoring-Skip (default: disabled)
+	ForceHeader string // request header that forces logging despite SkipPaths, e.g. X-Monitoring-Force (default: disabled)
+
+	RestrictMethods    bool // store non-standard HTTP methods as "OTHER" (default: false)
+	CaptureStackTraces bool // recover panics and store their stack trace in response.stack (default: false)
+
+	// OperationNameExtractor names the logical operation stored as the log
+	// path (default: GraphQL operationName for requests to GraphQLPath).
+	OperationNameExtractor func(*fiber.Ctx) string
+
+	// GraphQLPath is the GraphQL endpoint, e.g. "/graphql", whose POSTs
+	// are grouped by operationName (default: "" = disabled).
+	GraphQLPath string
+
+	// KeyFunc tags each log with a key such as the service name or API
+	// version (default: "apis-traffic").
+	KeyFunc func(*fiber.Ctx) string
+
+	// IPEnricher adds data such as a GeoIP country/city to each log under
+	// request.geo. It runs in the writer goroutine, off the request path.
+	IPEnricher logwriter.IPEnricher
+
+	// SuccessFunc overrides how a response is classified as successful
+	// (default: status < 400).
+	SuccessFunc func(status int, c *fiber.Ctx) bool
+
+	CompressBodies    bool // gzip-compress large captured bodies (default: false)
+	CompressThreshold int  // body size in bytes above which compression applies (default: 4KB)
+
+	// API response options
+	TransformerSkipPaths []string // /api/monitoring paths (prefix or glob) returned without the BaseResponse wrapper
+	StrictPagination     bool     // reject invalid page/per_page with 400 instead of using defaults (default: false)
+
+	// Analytics options
+	MethodGroups         map[string]string // fold methods in Analyze stats, e.g. {"HEAD": "GET"} (default: none)
+	ExceptionStatusCodes []int             // status codes counted as exceptions (default: [500])
+	MaxConcurrentAnalyze int               // max in-flight /requests/analyze calls; excess get 429 (default: 4)
+	AnalyzeCacheTTL      time.Duration     // serve repeated /requests/analyze calls from memory (default: 0 = off)
+	DefaultLookback      time.Duration     // date range used when fromDate is omitted (default: 24h)
+	MaxAnalyzeRange      time.Duration     // longest fromDate..toDate span for analyze/export; longer gets 400 (default: 0 = unlimited)
+	RelatedStrategy      string            // /requests/view/:id/related correlation: "trace", "user" or "auto" (default: auto)
+	RelatedWindow        time.Duration     // same-user requests within ± this long count as related (default: 5m)
+	RelatedUserField     string            // dotted path in the stored user JSON identifying the user (default: "id")
+	ExcludeFromAnalytics []string          // path prefixes ("*" wildcard) left out of analytics but kept in /requests (default: none)
+}
+
+// CORSConfig allows a dashboard hosted on another origin to call the
+// /api/monitoring routes. With no AllowOrigins only same-origin requests
+// work.
+type CORSConfig struct {
+	AllowOrigins     []string // e.g. ["https://dashboard.example.com"]; with "*", AllowCredentials is ignored
+	AllowCredentials bool     // allow cookies / Authorization with cross-origin requests
+}
+
+// Default mount points of the dashboard and the monitoring API.
+const (
+	DefaultDashboardRoute = "/monitoring"
+	DefaultAPIBasePath    = "/api/monitoring"
+)
+
+// routePrefix normalizes a mount point to a leading slash and no
+// trailing slash, using def when p is empty or "/".
+func routePrefix(p, def string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return def
+	}
+	return "/" + p
+}
+
+// defaultSkipPaths is the SkipPaths default for the given mount points.
+func defaultSkipPaths(apiBase, dashboardRoute string) []string {
+	return []string{apiBase, dashboardRoute, "/.well-known"}
+}
+
+// defaultTransformerSkipPaths is the TransformerSkipPaths default for the
+// given API base path.
+func defaultTransformerSkipPaths(apiBase string) []string {
+	return []string{apiBase + "/requests/export"}
+}
+
+// envMounts returns the API base path and dashboard route from the env.
+func envMounts() (apiBase, dashboardRoute string) {
+	return routePrefix(envStr("MONITORING_API_BASE_PATH", ""), DefaultAPIBasePath),
+		routePrefix(envStr("MONITORING_DASHBOARD_ROUTE", ""), DefaultDashboardRoute)
+}
+
+// mountPaths returns SkipPaths and TransformerSkipPaths for the mount
+// points apiBase and dashboardRoute. Lists that are nil or still the
+// defaults DefaultConfig derives (from the package defaults or the env)
+// are rebuilt, so changing APIBasePath or DashboardRoute after
+// DefaultConfig keeps the monitoring routes out of the logs. Lists the
+// caller set are returned unchanged.
+func (c *Config) mountPaths(apiBase, dashboardRoute string) (skip, transformerSkip []string) {
+	envAPI, envDashboard := envMounts()
+	skip, transformerSkip = c.SkipPaths, c.TransformerSkipPaths
+	if skip == nil ||
+		slices.Equal(skip, defaultSkipPaths(DefaultAPIBasePath, DefaultDashboardRoute)) ||
+		slices.Equal(skip, defaultSkipPaths(envAPI, envDashboard)) {
+		skip = defaultSkipPaths(apiBase, dashboardRoute)
+	}
+	if transformerSkip == nil ||
+		slices.Equal(transformerSkip, defaultTransformerSkipPaths(DefaultAPIBasePath)) ||
+		slices.Equal(transformerSkip, defaultTransformerSkipPaths(envAPI)) {
+		transformerSkip = defaultTransformerSkipPaths(apiBase)
+	}
+	return skip, transformerSkip
 }
 
 // DefaultConfig returns a Config populated from environment variables with sensible defaults.
 func DefaultConfig() *Config {
+	apiBasePath, dashboardRoute := envMounts()
 	return &Config{
 		RequestSaveEnabled: envBool("MONITORING_REQUEST_SAVE_ENABLED", true),
+		TablePrefix:        envStr("MONITORING_TABLE_PREFIX", ""),
+		UTCTimestamps:      envBool("MONITORING_UTC_TIMESTAMPS", false),
 		DashboardEnabled:   envBool("MONITORING_DASHBOARD_ENABLED", true),
 		DashboardPath:      envStr("MONITORING_DASHBOARD_PATH", ""),
+		DashboardRoute:     dashboardRoute,
+		APIBasePath:        apiBasePath,
 		AuthRequired:       envBool("MONITORING_AUTH_REQUIRED", false),
 		APIsEnabled:        envBool("MONITORING_APIS_ENABLED", true),
+		HealthGuarded:      envBool("MONITORING_HEALTH_GUARDED", false),
+		ViewerUsername:     envStr("MONITORING_VIEWER_USERNAME", ""),
+		ViewerPassword:     envStr("MONITORING_VIEWER_PASSWORD", ""),
+		RoleClaim:          envStr("MONITORING_ROLE_CLAIM", "role"),
+		DefaultRole:        envStr("MONITORING_DEFAULT_ROLE", auth.RoleViewer),
 		Username:           envStr("MONITORING_USERNAME", "admin"),
 		Password:           envStr("MONITORING_PASSWORD", "admin"),
 		JWTSecret:          envStr("MONITORING_JWT_SECRET", "monitoring-secret-change-me"),
+		JWKSURL:            envStr("MONITORING_JWKS_URL", ""),
+		JWKSIssuer:         envStr("MONITORING_JWKS_ISSUER", ""),
+		JWKSAudience:       envStr("MONITORING_JWKS_AUDIENCE", ""),
+
+		DashboardCORS: CORSConfig{
+			AllowOrigins:     envList("MONITORING_CORS_ORIGINS"),
+			AllowCredentials: envBool("MONITORING_CORS_CREDENTIALS", false),
+		},
+
+		LoginRateLimit:  envInt("MONITORING_LOGIN_RATE_LIMIT", 5),
+		LoginRateWindow: time.Duration(envInt("MONITORING_LOGIN_RATE_WINDOW_MS", 60000)) * time.Millisecond,
+
+		AnalyticsRateLimit:  envInt("MONITORING_ANALYTICS_RATE_LIMIT", 0),
+		AnalyticsRateWindow: time.Duration(envInt("MONITORING_ANALYTICS_RATE_WINDOW_MS", 60000)) * time.Millisecond,
 
 		BufferSize:    envInt("MONITORING_BUFFER_SIZE", 10000),
 		BatchSize:     envInt("MONITORING_BATCH_SIZE", 100),
 		FlushInterval: time.Duration(envInt("MONITORING_FLUSH_INTERVAL_MS", 5000)) * time.Millisecond,
 		Workers:       envInt("MONITORING_WORKERS", 1),
+		MaxBatchBytes: envInt("MONITORING_MAX_BATCH_BYTES", 0),
+		MaxDBConns:    envInt("MONITORING_MAX_DB_CONNS", 0),
+		DedupWrites:   envBool("MONITORING_DEDUP_WRITES", false),
+		RecentWindow:  time.Duration(envInt("MONITORING_RECENT_WINDOW_MS", 60000)) * time.Millisecond,
+
+		InsertChunkSize:     envInt("MONITORING_INSERT_CHUNK_SIZE", 50),
+		OnConflictDoNothing: envBool("MONITORING_ON_CONFLICT_DO_NOTHING", false),
+
+		OverflowPolicy: logwriter.OverflowPolicy(envStr("MONITORING_OVERFLOW_POLICY", string(logwriter.OverflowDrop))),
+		BlockTimeout:   time.Duration(envInt("MONITORING_BLOCK_TIMEOUT_MS", 100)) * time.Millisecond,
+
+		ShutdownTimeout: time.Duration(envInt("MONITORING_SHUTDOWN_TIMEOUT_MS", 10000)) * time.Millisecond,
+		HandleSignals:   envBool("MONITORING_HANDLE_SIGNALS", false),
+
+		FallbackCapacity: envInt("MONITORING_FALLBACK_CAPACITY", 0),
+
+		OTLPEndpoint:    envStr("MONITORING_OTLP_ENDPOINT", ""),
+		OTLPServiceName: envStr("MONITORING_OTLP_SERVICE_NAME", "go-monitoring"),
 
-		SkipPaths:       []string{"/api/monitoring", "/monitoring", "/.well-known"},
+		SkipPaths:       defaultSkipPaths(apiBasePath, dashboardRoute),
 		UserContextKey:  "user",
 		MaxBodySize:     64 * 1024, // 64KB
+		MaxReqBodySize:  envInt("MONITORING_MAX_REQ_BODY_SIZE", 0),
+		MaxRespBodySize: envInt("MONITORING_MAX_RESP_BODY_SIZE", 0),
 		CaptureReqBody:  true,
 		CaptureRespBody: true,
+		TraceHeader:     envStr("MONITORING_TRACE_HEADER", ""),
+
+		CaptureReqBodyOnErrorOnly:  envBool("MONITORING_CAPTURE_REQ_BODY_ON_ERROR_ONLY", false),
+		CaptureRespBodyOnErrorOnly: envBool("MONITORING_CAPTURE_RESP_BODY_ON_ERROR_ONLY", false),
+		CaptureBodyOverDurationMs:  float64(envInt("MONITORING_CAPTURE_BODY_OVER_DURATION_MS", 0)),
+
+		ExposeBufferHeader: envBool("MONITORING_EXPOSE_BUFFER_HEADER", false),
+		SkipHeader:         envStr("MONITORING_SKIP_HEADER", ""),
+		ForceHeader:        envStr("MONITORING_FORCE_HEADER", ""),
+		RestrictMethods:    envBool("MONITORING_RESTRICT_METHODS", false),
+		GraphQLPath:        envStr("MONITORING_GRAPHQL_PATH", ""),
+		CaptureStackTraces: envBool("MONITORING_CAPTURE_STACK_TRACES", false),
+
+		CompressBodies:    envBool("MONITORING_COMPRESS_BODIES", false),
+		CompressThreshold: envInt("MONITORING_COMPRESS_THRESHOLD", 4*1024),
+
+		TransformerSkipPaths: defaultTransformerSkipPaths(apiBasePath),
+		StrictPagination:     envBool("MONITORING_STRICT_PAGINATION", false),
+		ExceptionStatusCodes: []int{500},
+		MaxConcurrentAnalyze: envInt("MONITORING_MAX_CONCURRENT_ANALYZE", 4),
+		AnalyzeCacheTTL:      time.Duration(envInt("MONITORING_ANALYZE_CACHE_TTL_MS", 0)) * time.Millisecond,
+		DefaultLookback:      time.Duration(envInt("MONITORING_DEFAULT_LOOKBACK_MS", 24*60*60*1000)) * time.Millisecond,
+		MaxAnalyzeRange:      time.Duration(envInt("MONITORING_MAX_ANALYZE_RANGE_MS", 0)) * time.Millisecond,
+		RelatedStrategy:      envStr("MONITORING_RELATED_STRATEGY", "auto"),
+		RelatedWindow:        time.Duration(envInt("MONITORING_RELATED_WINDOW_MS", 5*60*1000)) * time.Millisecond,
+		RelatedUserField:     envStr("MONITORING_RELATED_USER_FIELD", "id"),
+		ExcludeFromAnalytics: envList("MONITORING_EXCLUDE_FROM_ANALYTICS"),
 	}
 }
 
@@ -83,6 +349,16 @@ func envStr(key, def string) string {
 	return v
 }
 
+func envList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func envInt(key string, def int) int {
 	v := os.Getenv(key)
 	if v == "" {
@@ -0,0 +1,27 @@
+package monitoring
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMountPaths(t *testing.T) {
+	c := DefaultConfig()
+	c.APIBasePath = "/internal/mon"
+	c.DashboardRoute = "/mon"
+
+	skip, transformerSkip := c.mountPaths("/internal/mon", "/mon")
+	if want := []string{"/internal/mon", "/mon", "/.well-known"}; !slices.Equal(skip, want) {
+		t.Errorf("skip paths = %v, want %v", skip, want)
+	}
+	if want := []string{"/internal/mon/requests/export"}; !slices.Equal(transformerSkip, want) {
+		t.Errorf("transformer skip paths = %v, want %v", transformerSkip, want)
+	}
+
+	c.SkipPaths = []string{"/api/monitoring"}
+	c.TransformerSkipPaths = []string{}
+	skip, transformerSkip = c.mountPaths("/internal/mon", "/mon")
+	if !slices.Equal(skip, c.SkipPaths) || transformerSkip == nil || len(transformerSkip) != 0 {
+		t.Errorf("custom lists were replaced: %v, %v", skip, transformerSkip)
+	}
+}
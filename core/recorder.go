@@ -0,0 +1,192 @@
+// Package core contains the framework-independent capture pipeline.
+//
+// Adapters (the Fiber middleware, net/http or Echo wrappers, manual
+// instrumentation) translate whatever they observe into a RequestLogInput
+// and hand it to a Recorder, which builds the stored RequestLog, applies
+// body compression, and enqueues it on the async Writer.
+package core
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/logwriter"
+	"github.com/aghiadodeh/go-monitoring/models"
+	"github.com/aghiadodeh/go-monitoring/otel"
+	"gorm.io/datatypes"
+)
+
+// DefaultKey is the log key used when RequestLogInput.Key is empty.
+const DefaultKey = "apis-traffic"
+
+// RequestLogInput is a framework-agnostic description of one handled
+// request. Only Method, Path and StatusCode are required.
+type RequestLogInput struct {
+	Key        string        // log key (default: "apis-traffic")
+	Method     string        // HTTP method or RPC verb
+	Path       string        // normalized route used for grouping
+	URL        string        // full URL (default: Path)
+	StatusCode int           // response status code
+	Duration   time.Duration // handler duration
+	Success    *bool         // default: StatusCode < 400
+	StartedAt  time.Time     // default: now - Duration
+	TraceID    string
+
+	IP              string
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
+	Params          map[string]string // matched route parameters
+	Queries         map[string]string
+	User            any             // marshaled to JSON, nil = no user
+	RequestBody     json.RawMessage // must be valid JSON if set
+	ResponseBody    json.RawMessage // must be valid JSON if set
+	Exception       string
+	Stack           string // Go stack trace of a recovered panic
+
+	// Timings is an optional server-timing style breakdown of Duration,
+	// e.g. {"db": 40ms, "cache": 5ms}. Stored in milliseconds.
+	Timings map[string]time.Duration
+
+	// Tags are free-form labels (e.g. {"checkout-flow": true,
+	// "experiment": "B"}) used to slice logs later.
+	Tags map[string]any
+
+	// IndexedFields are request body values stored in a searchable
+	// column, e.g. {"orderId": "123"}.
+	IndexedFields map[string]string
+}
+
+// Recorder turns RequestLogInput values into stored logs. It is safe for
+// concurrent use.
+type Recorder struct {
+	Writer *logwriter.Writer
+	Spans  *otel.Exporter // optional; nil disables span export
+
+	// CompressBodies gzip-compresses bodies larger than CompressThreshold
+	// bytes (default: 4KB) before storing them.
+	CompressBodies    bool
+	CompressThreshold int
+}
+
+// Record builds a RequestLog from in and enqueues it. It never blocks;
+// the entry is dropped if the writer buffer is full.
+func (r *Recorder) Record(in RequestLogInput) {
+	entry, start := r.build(in)
+	r.Writer.Write(entry)
+	r.Spans.Export(otel.Span{
+		TraceID:    in.TraceID,
+		Name:       entry.Path,
+		Method:     entry.Method,
+		StatusCode: in.StatusCode,
+		Start:      start,
+		End:        start.Add(in.Duration),
+	})
+}
+
+// Stats returns the underlying writer's buffer snapshot.
+func (r *Recorder) Stats() logwriter.Stats {
+	return r.Writer.Stats()
+}
+
+func (r *Recorder) build(in RequestLogInput) (models.RequestLog, time.Time) {
+	if in.Key == "" {
+		in.Key = DefaultKey
+	}
+	if in.URL == "" {
+		in.URL = in.Path
+	}
+	if in.StartedAt.IsZero() {
+		in.StartedAt = time.Now().Add(-in.Duration)
+	}
+	success := in.StatusCode < 400
+	if in.Success != nil {
+		success = *in.Success
+	}
+
+	var exception interface{}
+	if in.Exception != "" {
+		exception = in.Exception
+	}
+
+	userJSON := []byte("null")
+	if in.User != nil {
+		if b, err := json.Marshal(in.User); err == nil {
+			userJSON = b
+		}
+	}
+
+	requestJSON, _ := json.Marshal(map[string]interface{}{
+		"ip":       in.IP,
+		"headers":  in.RequestHeaders,
+		"params":   in.Params,
+		"queries":  in.Queries,
+		"body":     r.maybeCompress(in.RequestBody),
+		"datetime": in.StartedAt,
+	})
+
+	response := map[string]interface{}{
+		"statusCode": in.StatusCode,
+		"body":       r.maybeCompress(in.ResponseBody),
+		"exception":  exception,
+		"datetime":   in.StartedAt.Add(in.Duration),
+	}
+	if in.Stack != "" {
+		response["stack"] = in.Stack
+	}
+	responseJSON, _ := json.Marshal(response)
+
+	respHeadersJSON, _ := json.Marshal(in.ResponseHeaders)
+	routeParamsJSON, _ := json.Marshal(in.Params)
+
+	var timingsJSON datatypes.JSON
+	if len(in.Timings) > 0 {
+		ms := make(map[string]float64, len(in.Timings))
+		for name, d := range in.Timings {
+			ms[name] = float64(d.Microseconds()) / 1000
+		}
+		timingsJSON, _ = json.Marshal(ms)
+	}
+
+	var tagsJSON datatypes.JSON
+	if len(in.Tags) > 0 {
+		tagsJSON, _ = json.Marshal(in.Tags)
+	}
+
+	var indexedJSON datatypes.JSON
+	if len(in.IndexedFields) > 0 {
+		indexedJSON, _ = json.Marshal(in.IndexedFields)
+	}
+
+	return models.RequestLog{
+		Key:             in.Key,
+		Path:            in.Path,
+		URL:             in.URL,
+		Method:          in.Method,
+		User:            datatypes.JSON(userJSON),
+		Request:         datatypes.JSON(requestJSON),
+		Response:        datatypes.JSON(responseJSON),
+		ResponseHeaders: datatypes.JSON(respHeadersJSON),
+		RouteParams:     datatypes.JSON(routeParamsJSON),
+		Timings:         timingsJSON,
+		Tags:            tagsJSON,
+		IndexedFields:   indexedJSON,
+		Success:         success,
+		Duration:        float64(in.Duration.Milliseconds()),
+		TraceID:         in.TraceID,
+		IP:              in.IP,
+		StartedAt:       in.StartedAt,
+	}, in.StartedAt
+}
+
+// maybeCompress wraps body in a gzip envelope when compression is
+// enabled and the body exceeds the configured threshold.
+func (r *Recorder) maybeCompress(body json.RawMessage) json.RawMessage {
+	threshold := r.CompressThreshold
+	if threshold <= 0 {
+		threshold = 4 * 1024
+	}
+	if !r.CompressBodies || len(body) <= threshold {
+		return body
+	}
+	return models.CompressBody(body)
+}
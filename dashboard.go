@@ -0,0 +1,227 @@
+package monitoring
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// assetCacheControl is sent with static dashboard assets. index.html is
+// always revalidated so a new dashboard build is picked up immediately.
+const (
+	assetCacheControl = "public, max-age=86400"
+	indexCacheControl = "no-cache"
+)
+
+// dashboardAsset is a static file prepared for serving.
+type dashboardAsset struct {
+	content     []byte
+	gzipped     []byte // nil when not worth compressing
+	contentType string
+	etag        string
+	modTime     time.Time
+}
+
+// gzipMinSize is the smallest asset worth compressing.
+const gzipMinSize = 1024
+
+// compressible reports whether assets of contentType benefit from gzip.
+func compressible(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.HasPrefix(ct, "text/") ||
+		strings.Contains(ct, "javascript") ||
+		strings.Contains(ct, "json") ||
+		strings.Contains(ct, "xml") ||
+		strings.Contains(ct, "svg")
+}
+
+// gzipBytes compresses b, returning nil if compression fails or does
+// not make the content smaller.
+func gzipBytes(b []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err := zw.Write(b); err != nil {
+		return nil
+	}
+	if err := zw.Close(); err != nil {
+		return nil
+	}
+	if buf.Len() >= len(b) {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// dashboard serves the SPA from an fs.FS with validators (ETag,
+// Last-Modified) so browsers can revalidate instead of refetching.
+type dashboard struct {
+	fsys fs.FS
+
+	// cache holds prepared assets. It is only used for the embedded
+	// assets, which never change at runtime; a DashboardPath override is
+	// re-read on every request so edits show up during development.
+	cacheable bool
+	startedAt time.Time
+	mu        sync.RWMutex
+	cache     map[string]*dashboardAsset
+
+	// route is the URL prefix the dashboard is mounted under; index.html's
+	// <base href> is rewritten to it so assets resolve under a custom route.
+	route string
+}
+
+func newDashboard(fsys fs.FS, cacheable bool, route string) *dashboard {
+	return &dashboard{
+		fsys:      fsys,
+		cacheable: cacheable,
+		startedAt: time.Now().UTC().Truncate(time.Second),
+		cache:     make(map[string]*dashboardAsset),
+		route:     route,
+	}
+}
+
+// builtBaseHref is the <base> tag the embedded dashboard is built with.
+const builtBaseHref = `<base href="/monitoring/">`
+
+// asset loads name from the filesystem (or cache).
+func (d *dashboard) asset(name string) (*dashboardAsset, error) {
+	if d.cacheable {
+		d.mu.RLock()
+		a, ok := d.cache[name]
+		d.mu.RUnlock()
+		if ok {
+			return a, nil
+		}
+	}
+
+	content, err := fs.ReadFile(d.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "index.html" && d.route != "" {
+		content = bytes.Replace(content, []byte(builtBaseHref), []byte(`<base href="`+d.route+`/">`), 1)
+	}
+
+	// Embedded files carry no modification time; use the process start.
+	modTime := d.startedAt
+	if info, err := fs.Stat(d.fsys, name); err == nil && !info.ModTime().IsZero() {
+		modTime = info.ModTime().UTC().Truncate(time.Second)
+	}
+
+	sum := sha256.Sum256(content)
+	a := &dashboardAsset{
+		content:     content,
+		contentType: mime.TypeByExtension(path.Ext(name)),
+		etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		modTime:     modTime,
+	}
+	if name == "index.html" {
+		a.contentType = fiber.MIMETextHTMLCharsetUTF8
+	}
+	// Compressed once per asset and cached alongside it.
+	if len(content) >= gzipMinSize && compressible(a.contentType) {
+		a.gzipped = gzipBytes(content)
+	}
+
+	if d.cacheable {
+		d.mu.Lock()
+		d.cache[name] = a
+		d.mu.Unlock()
+	}
+	return a, nil
+}
+
+// send writes a with its validators, answering 304 when the client's
+// If-None-Match matches. Clients accepting gzip get the compressed
+// variant, which carries its own ETag.
+func (d *dashboard) send(ctx *fiber.Ctx, a *dashboardAsset, cacheControl string) error {
+	body, etag := a.content, a.etag
+	if a.gzipped != nil {
+		ctx.Vary(fiber.HeaderAcceptEncoding)
+		if acceptsGzip(ctx.Get(fiber.HeaderAcceptEncoding)) {
+			body = a.gzipped
+			etag = strings.TrimSuffix(a.etag, `"`) + `-gzip"`
+			ctx.Set(fiber.HeaderContentEncoding, "gzip")
+		}
+	}
+
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderLastModified, a.modTime.Format(http.TimeFormat))
+	ctx.Set(fiber.HeaderCacheControl, cacheControl)
+	if a.contentType != "" {
+		ctx.Set(fiber.HeaderContentType, a.contentType)
+	}
+
+	if etagMatches(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+	return ctx.Send(body)
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		// Honour an explicit refusal such as "gzip;q=0".
+		q := strings.ReplaceAll(strings.ToLower(params), " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// serveIndex sends index.html. It is used both for the base route and
+// as the SPA fallback.
+func (d *dashboard) serveIndex(ctx *fiber.Ctx) error {
+	a, err := d.asset("index.html")
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("Dashboard not found")
+	}
+	return d.send(ctx, a, indexCacheControl)
+}
+
+// serveFile serves a static file if it exists, otherwise falls back to
+// index.html for SPA client-side routing.
+func (d *dashboard) serveFile(ctx *fiber.Ctx) error {
+	cleanPath := path.Clean(ctx.Params("*"))
+
+	// Prevent path traversal
+	if cleanPath == "." || strings.HasPrefix(cleanPath, "..") || cleanPath == "index.html" {
+		return d.serveIndex(ctx)
+	}
+
+	a, err := d.asset(cleanPath)
+	if err != nil {
+		// File not found → SPA fallback
+		return d.serveIndex(ctx)
+	}
+	return d.send(ctx, a, assetCacheControl)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
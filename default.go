@@ -0,0 +1,57 @@
+package monitoring
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrNotSetup is returned by the package-level helpers when no default
+// Monitor exists because Setup has not been called.
+var ErrNotSetup = errors.New("monitoring: Setup has not been called")
+
+// defaultMonitor is the Monitor used by the package-level helpers. Setup
+// stores the Monitor it returns here; the last call wins.
+var defaultMonitor atomic.Pointer[Monitor]
+
+// Default returns the Monitor created by the most recent Setup call, or
+// nil if Setup has not been called.
+func Default() *Monitor {
+	return defaultMonitor.Load()
+}
+
+// SetDefault replaces the Monitor used by the package-level helpers, e.g.
+// to point them at a Monitor in tests. Passing nil clears it.
+func SetDefault(m *Monitor) {
+	defaultMonitor.Store(m)
+}
+
+// LogJob records a job execution on the default Monitor, so code deep in
+// the call tree can log jobs without being handed the *Monitor.
+//
+//	monitoring.LogJob("send-emails", true, map[string]any{"count": 42})
+func LogJob(name string, success bool, metadata interface{}) error {
+	m := Default()
+	if m == nil {
+		return ErrNotSetup
+	}
+	return m.LogJob(name, success, metadata)
+}
+
+// LogJobs records many job executions on the default Monitor.
+func LogJobs(entries []JobLogInput) error {
+	m := Default()
+	if m == nil {
+		return ErrNotSetup
+	}
+	return m.LogJobs(entries)
+}
+
+// LogRequest enqueues a request log on the default Monitor.
+func LogRequest(in RequestLogInput) error {
+	m := Default()
+	if m == nil {
+		return ErrNotSetup
+	}
+	m.LogRequest(in)
+	return nil
+}
@@ -0,0 +1,18 @@
+package dto
+
+// AnalyzeOptions extends BaseFilter with optional filters that narrow the
+// dataset analysed by /requests/analyze.
+type AnalyzeOptions struct {
+	BaseFilter
+	Method       string `query:"method"`       // comma-separated: "GET,POST"
+	URL          string `query:"url"`          // substring match on the full URL
+	SuccessOnly  bool   `query:"successOnly"`  // only successful requests
+	DurationUnit string `query:"durationUnit"` // "ms" (default), "s" or "us"
+	Format       string `query:"format"`       // "histogram" flattens duration buckets to {from, to, count}
+	TZ           string `query:"tz"`           // IANA zone that time buckets align to, e.g. "Europe/Berlin"
+	Granularity  string `query:"granularity"`  // minute, hour, day, week or month; overrides the automatic time bucket step
+
+	// MaxBucketItems caps the sample requests embedded in each duration
+	// and time bucket's Data; Count stays exact (default: 50).
+	MaxBucketItems int `query:"maxBucketItems"`
+}
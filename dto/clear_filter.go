@@ -0,0 +1,9 @@
+package dto
+
+// ClearFilter holds the optional query params for DELETE /clear.
+type ClearFilter struct {
+	Before string `query:"before"` // RFC3339; only rows created before this time
+	Key    string `query:"key"`    // only request logs with this key
+	Tables string `query:"tables"` // "requests", "jobs" or "both" (default)
+	DryRun bool   `query:"dryRun"` // only report how many rows would be deleted
+}
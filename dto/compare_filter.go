@@ -0,0 +1,16 @@
+package dto
+
+// CompareFilter selects the two date ranges compared by
+// /requests/analyze/compare. fromDate/toDate give the current range;
+// prevFromDate/prevToDate the previous one, which defaults to the
+// window of equal length immediately before the current range.
+type CompareFilter struct {
+	BaseFilter
+	PrevFromDate string `query:"prevFromDate"`
+	PrevToDate   string `query:"prevToDate"`
+}
+
+// Previous returns the previous range as a BaseFilter.
+func (f CompareFilter) Previous() BaseFilter {
+	return BaseFilter{FromDate: f.PrevFromDate, ToDate: f.PrevToDate}
+}
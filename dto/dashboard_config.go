@@ -0,0 +1,21 @@
+package dto
+
+// DashboardConfig is the public subset of the effective configuration
+// returned by GET /config so the dashboard can adapt its UI. It must
+// never carry secrets.
+type DashboardConfig struct {
+	AuthRequired               bool      `json:"authRequired"`
+	LoginEnabled               bool      `json:"loginEnabled"` // built-in username/password login is in use
+	APIsEnabled                bool      `json:"apisEnabled"`
+	HealthGuarded              bool      `json:"healthGuarded"`
+	RequestSaveEnabled         bool      `json:"requestSaveEnabled"`
+	CaptureReqBody             bool      `json:"captureReqBody"`
+	CaptureRespBody            bool      `json:"captureRespBody"`
+	CaptureReqBodyOnErrorOnly  bool      `json:"captureReqBodyOnErrorOnly"`
+	CaptureRespBodyOnErrorOnly bool      `json:"captureRespBodyOnErrorOnly"`
+	CaptureBodyOverDurationMs  float64   `json:"captureBodyOverDurationMs"`
+	DurationBoundaries         []float64 `json:"durationBoundaries"` // ms
+	DefaultLookback            int64     `json:"defaultLookback"`    // ms
+	DashboardRoute             string    `json:"dashboardRoute"`
+	APIBasePath                string    `json:"apiBasePath"`
+}
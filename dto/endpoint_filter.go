@@ -0,0 +1,9 @@
+package dto
+
+// EndpointFilter extends AnalyzeOptions with thresholds for the
+// per-endpoint analytics in /requests/analyze/endpoints.
+type EndpointFilter struct {
+	AnalyzeOptions
+	MinErrorRate *float64 `query:"minErrorRate"` // only endpoints with error rate (0–100) above this
+	MinCount     *int64   `query:"minCount"`     // only endpoints with at least this many requests
+}
@@ -2,6 +2,25 @@ package dto
 
 // ListResponse is a generic paginated list response.
 type ListResponse[T any] struct {
-	Total int64 `json:"total"`
-	Data  []T   `json:"data"`
+	Total      int64 `json:"total"`
+	Data       []T   `json:"data"`
+	Page       int   `json:"page"`
+	PerPage    int   `json:"perPage"`
+	TotalPages int   `json:"totalPages"`
+}
+
+// NewListResponse builds a ListResponse and derives TotalPages from
+// total and perPage.
+func NewListResponse[T any](data []T, total int64, page, perPage int) *ListResponse[T] {
+	totalPages := 0
+	if perPage > 0 {
+		totalPages = int((total + int64(perPage) - 1) / int64(perPage))
+	}
+	return &ListResponse[T]{
+		Total:      total,
+		Data:       data,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: totalPages,
+	}
 }
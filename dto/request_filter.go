@@ -3,12 +3,23 @@ package dto
 // RequestFilter extends BaseFilter with request-specific query params.
 type RequestFilter struct {
 	BaseFilter
-	URL        string   `query:"url"`
-	Method     string   `query:"method"`     // comma-separated: "GET,POST"
-	Exception  *bool    `query:"exception"`  // true → only status 500
-	Success    *bool    `query:"success"`
-	User       string   `query:"user"`
-	DurationGt *float64 `query:"durationGt"` // duration >= value (ms)
-	DurationLt *float64 `query:"durationLt"` // duration <= value (ms)
-	StatusCode *int     `query:"statusCode"`
+	URL          string   `query:"url"`
+	Method       string   `query:"method"`       // comma-separated: "GET,POST"
+	Exception    *bool    `query:"exception"`    // true → only exception status codes (default: 500)
+	HasException *bool    `query:"hasException"` // true → only logs with a captured Go error, whatever the status
+	Success      *bool    `query:"success"`
+	User         string   `query:"user"`
+	DurationGt   *float64 `query:"durationGt"` // duration >= value (ms)
+	DurationLt   *float64 `query:"durationLt"` // duration <= value (ms)
+	StatusCode   *int     `query:"statusCode"`
+	StatusCodes  string   `query:"statusCodes"` // comma-separated: "400,401,403"
+	TraceID      string   `query:"traceId"`
+	Key          string   `query:"key"`
+	Param        string   `query:"param"`        // route param match "key:value", comma-separated for several
+	Tag          string   `query:"tag"`          // "name" or "name:value", comma-separated for several (all must match)
+	Field        string   `query:"field"`        // indexed body field match "key:value", comma-separated for several
+	UserField    string   `query:"userField"`    // dotted path inside the stored user JSON, e.g. "role"
+	UserValue    string   `query:"userValue"`    // value UserField must equal
+	DurationUnit string   `query:"durationUnit"` // "ms" (default), "s" or "us"
+	Fields       string   `query:"fields"`       // comma-separated subset of fields to return, e.g. "id,method,path"
 }
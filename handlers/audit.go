@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aghiadodeh/go-monitoring/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AuditLogin returns a middleware for the login route that records every
+// attempt, successful or not, in the audit trail.
+func AuditLogin(svc *services.JobService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body struct {
+			Username string `json:"username"`
+		}
+		_ = c.BodyParser(&body)
+
+		err := c.Next()
+
+		// Errors returned by the handler have not been written yet.
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		success := err == nil && status == fiber.StatusOK
+		audit(c, svc, success, services.AuditEntry{
+			Action: "login",
+			User:   body.Username,
+			IP:     c.IP(),
+			Detail: map[string]int{"statusCode": status},
+		})
+		return err
+	}
+}
+
+// audit writes an audit entry, logging rather than failing the request
+// when the write fails.
+func audit(c *fiber.Ctx, svc *services.JobService, success bool, e services.AuditEntry) {
+	if err := svc.Audit(c.UserContext(), success, e); err != nil {
+		log.Printf("[go-monitoring] error writing audit log for %s: %v\n", e.Action, err)
+	}
+}
+
+// auditUser identifies the authenticated caller from the guard's claims.
+func auditUser(c *fiber.Ctx) string {
+	var claims map[string]any
+	switch v := c.Locals("monitoring_user").(type) {
+	case jwt.MapClaims:
+		claims = v
+	case map[string]any:
+		claims = v
+	}
+	for _, k := range []string{"sub", "email", "id"} {
+		if v, ok := claims[k]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"github.com/aghiadodeh/go-monitoring/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ConfigHandler exposes the dashboard-facing configuration.
+type ConfigHandler struct {
+	Config dto.DashboardConfig
+}
+
+// Get handles GET /config
+func (h *ConfigHandler) Get(c *fiber.Ctx) error {
+	return c.JSON(h.Config)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/logwriter"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// HealthHandler reports the health of the monitoring subsystem itself.
+type HealthHandler struct {
+	DB     *gorm.DB
+	Writer *logwriter.Writer
+}
+
+// Health handles GET /health
+func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	dbStatus := "ok"
+	if err := h.ping(c.UserContext()); err != nil {
+		dbStatus = "down"
+	}
+
+	stats := h.Writer.Stats()
+	return c.JSON(fiber.Map{
+		"db":           dbStatus,
+		"writerBuffer": fmt.Sprintf("%d/%d", stats.Buffered, stats.Capacity),
+		"dropped":      stats.Dropped,
+		"fallback":     stats.FallbackDepth,
+		"rejected":     stats.Rejected,
+		"lagSeconds":   stats.LagSeconds,
+	})
+}
+
+// Healthz handles GET /healthz
+// It reports the same state as Health in the Prometheus text format, so
+// operators can scrape it and alert on monitoring_writer_lag_seconds.
+func (h *HealthHandler) Healthz(c *fiber.Ctx) error {
+	up := 1
+	if err := h.ping(c.UserContext()); err != nil {
+		up = 0
+	}
+	stats := h.Writer.Stats()
+
+	var b strings.Builder
+	metric := func(name, typ, help string, value any) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+	}
+	metric("monitoring_db_up", "gauge", "Whether the monitoring database answers a ping.", up)
+	metric("monitoring_writer_buffered", "gauge", "Entries waiting in the writer buffer.", stats.Buffered)
+	metric("monitoring_writer_capacity", "gauge", "Capacity of the writer buffer.", stats.Capacity)
+	metric("monitoring_writer_lag_seconds", "gauge", "Age of the oldest buffered entry not yet stored.", stats.LagSeconds)
+	metric("monitoring_writer_fallback_batches", "gauge", "Failed batches waiting to be retried.", stats.FallbackDepth)
+	metric("monitoring_writer_dropped_total", "counter", "Entries dropped because the buffer was full.", stats.Dropped)
+	metric("monitoring_writer_rejected_total", "counter", "Entries rejected by the database.", stats.Rejected)
+
+	c.Locals("skipResponseTransform", true)
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.SendString(b.String())
+}
+
+// Now handles GET /now
+// It reports live traffic over the writer's recent window from memory.
+func (h *HealthHandler) Now(c *fiber.Ctx) error {
+	return c.JSON(h.Writer.Recent())
+}
+
+// ping runs a lightweight connectivity check against the database.
+func (h *HealthHandler) ping(ctx context.Context) error {
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
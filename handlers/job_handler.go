@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/aghiadodeh/go-monitoring/dto"
 	"github.com/aghiadodeh/go-monitoring/services"
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +11,10 @@ import (
 // JobHandler exposes REST endpoints for job logs.
 type JobHandler struct {
 	Service *services.JobService
+
+	// StrictPagination rejects invalid page/per_page values with 400
+	// instead of falling back to defaults.
+	StrictPagination bool
 }
 
 // FindAll handles GET /jobs
@@ -17,7 +23,25 @@ func (h *JobHandler) FindAll(c *fiber.Ctx) error {
 	if err := c.QueryParser(&f); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
 	}
-	result, err := h.Service.FindAll(f)
+	if h.StrictPagination {
+		if err := services.ValidatePagination(f.BaseFilter); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+	}
+	result, err := h.Service.FindAll(c.UserContext(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// Analyze handles GET /jobs/analyze
+func (h *JobHandler) Analyze(c *fiber.Ctx) error {
+	var f dto.BaseFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	result, err := h.Service.Analyze(c.UserContext(), f)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 	}
@@ -27,7 +51,7 @@ func (h *JobHandler) FindAll(c *fiber.Ctx) error {
 // FindByID handles GET /jobs/:id
 func (h *JobHandler) FindByID(c *fiber.Ctx) error {
 	id := c.Params("id")
-	result, err := h.Service.FindByID(id)
+	result, err := h.Service.FindByID(c.UserContext(), id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "not found"})
 	}
@@ -35,9 +59,47 @@ func (h *JobHandler) FindByID(c *fiber.Ctx) error {
 }
 
 // ClearAll handles DELETE /clear
+// Optional query params (before, key, tables) narrow what is deleted.
 func (h *JobHandler) ClearAll(c *fiber.Ctx) error {
-	if err := h.Service.ClearAll(); err != nil {
+	var f dto.ClearFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+
+	opts := services.ClearOptions{Key: f.Key, Tables: services.ClearTables(f.Tables)}
+	switch opts.Tables {
+	case "", services.ClearBoth, services.ClearRequests, services.ClearJobs:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "tables must be one of: requests, jobs, both"})
+	}
+	if f.Before != "" {
+		t, err := time.Parse(time.RFC3339, f.Before)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "before must be an RFC3339 timestamp"})
+		}
+		opts.Before = t
+	}
+
+	if f.DryRun {
+		requests, jobs, err := h.Service.Count(c.UserContext(), opts)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		}
+		return c.JSON(fiber.Map{"dryRun": true, "requests": requests, "jobs": jobs})
+	}
+
+	err := h.Service.Clear(c.UserContext(), opts)
+	audit(c, h.Service, err == nil, services.AuditEntry{
+		Action: "clear",
+		User:   auditUser(c),
+		IP:     c.IP(),
+		Detail: map[string]string{"before": f.Before, "key": f.Key, "tables": f.Tables},
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "all monitoring data cleared"})
+	if opts == (services.ClearOptions{}) {
+		return c.JSON(fiber.Map{"success": true, "message": "all monitoring data cleared"})
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "matching monitoring data cleared"})
 }
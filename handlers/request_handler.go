@@ -1,6 +1,12 @@
 package handlers
 
 import (
+	"bufio"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
 	"github.com/aghiadodeh/go-monitoring/dto"
 	"github.com/aghiadodeh/go-monitoring/services"
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +15,10 @@ import (
 // RequestHandler exposes REST endpoints for request logs.
 type RequestHandler struct {
 	Service *services.RequestService
+
+	// StrictPagination rejects invalid page/per_page values with 400
+	// instead of falling back to defaults.
+	StrictPagination bool
 }
 
 // FindAll handles GET /requests
@@ -17,20 +27,222 @@ func (h *RequestHandler) FindAll(c *fiber.Ctx) error {
 	if err := c.QueryParser(&f); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
 	}
-	result, err := h.Service.FindAll(f)
+	if h.StrictPagination {
+		if err := services.ValidatePagination(f.BaseFilter); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+	}
+	if _, err := services.DurationScale(f.DurationUnit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := validateRequestFilter(f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if f.Fields != "" {
+		fields, err := services.ParseFields(f.Fields)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+		result, err := h.Service.FindAllFields(c.UserContext(), f, fields)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		}
+		return c.JSON(result)
+	}
+	result, err := h.Service.FindAll(c.UserContext(), f)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 	}
 	return c.JSON(result)
 }
 
+// ExportNDJSON handles GET /requests/export/ndjson
+func (h *RequestHandler) ExportNDJSON(c *fiber.Ctx) error {
+	var f dto.RequestFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	if err := h.Service.CheckRange(f.BaseFilter); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	// Errors inside the stream writer can only be logged, so reject bad
+	// filters before the 200 is sent.
+	if err := validateRequestFilter(f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	// Streamed bodies must not be buffered by the response transformer.
+	c.Locals("skipResponseTransform", true)
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="requests.ndjson"`)
+	// The stream writer runs after the handler returns, so capture the
+	// context now rather than touching c from inside it.
+	ctx := c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.Service.ExportNDJSON(ctx, f, w); err != nil {
+			log.Printf("[go-monitoring] error exporting ndjson: %v\n", err)
+		}
+	})
+	return nil
+}
+
+// validateRequestFilter checks the filters that filterQuery would
+// otherwise reject only once the query runs.
+func validateRequestFilter(f dto.RequestFilter) error {
+	if f.UserField != "" {
+		if _, err := services.UserFieldPath(f.UserField); err != nil {
+			return err
+		}
+	}
+	if f.StatusCodes != "" {
+		if _, err := services.ParseStatusCodes(f.StatusCodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Analyze handles GET /requests/analyze
 func (h *RequestHandler) Analyze(c *fiber.Ctx) error {
+	var f dto.AnalyzeOptions
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	if _, err := services.DurationScale(f.DurationUnit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if f.Format != "" && f.Format != "histogram" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "format must be histogram or omitted"})
+	}
+	if err := h.Service.CheckRange(f.BaseFilter); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err := h.Service.CheckGranularity(f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	result, err := h.Service.Analyze(c.UserContext(), f)
+	if errors.Is(err, services.ErrAnalyzeBusy) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	if f.Format == "histogram" {
+		return c.JSON(services.HistogramAnalyzeResult{AnalyzeResult: result, Duration: result.Histogram()})
+	}
+	return c.JSON(result)
+}
+
+// AnalyzeByEndpoint handles GET /requests/analyze/endpoints
+func (h *RequestHandler) AnalyzeByEndpoint(c *fiber.Ctx) error {
+	var f dto.EndpointFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	if _, err := services.DurationScale(f.DurationUnit); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	result, err := h.Service.AnalyzeByEndpoint(c.UserContext(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// Compare handles GET /requests/analyze/compare
+func (h *RequestHandler) Compare(c *fiber.Ctx) error {
+	var f dto.CompareFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	result, err := h.Service.Compare(c.UserContext(), f.BaseFilter, f.Previous())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// SLOReport handles GET /requests/analyze/slo
+func (h *RequestHandler) SLOReport(c *fiber.Ctx) error {
 	var f dto.BaseFilter
 	if err := c.QueryParser(&f); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
 	}
-	result, err := h.Service.Analyze(f)
+	threshold, err := strconv.ParseFloat(c.Query("threshold"), 64)
+	if err != nil || threshold <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "threshold must be a positive number of milliseconds"})
+	}
+	result, err := h.Service.SLOReport(c.UserContext(), f, threshold)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// BucketItems handles GET /requests/analyze/bucket
+func (h *RequestHandler) BucketItems(c *fiber.Ctx) error {
+	var f dto.BaseFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	if h.StrictPagination {
+		if err := services.ValidatePagination(f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+	}
+	boundary, err := strconv.ParseFloat(c.Query("boundary"), 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "boundary must be a number of milliseconds"})
+	}
+	result, err := h.Service.BucketItems(c.UserContext(), boundary, f, c.QueryInt("page", 1))
+	if errors.Is(err, services.ErrInvalidBoundary) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// Facets handles GET /facets
+func (h *RequestHandler) Facets(c *fiber.Ctx) error {
+	var f dto.BaseFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	result, err := h.Service.Facets(c.UserContext(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// SlowestRequests handles GET /requests/slowest
+func (h *RequestHandler) SlowestRequests(c *fiber.Ctx) error {
+	var f dto.BaseFilter
+	if err := c.QueryParser(&f); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid query parameters"})
+	}
+	result, err := h.Service.SlowestRequests(c.UserContext(), f, c.QueryInt("limit", 10))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// Related handles GET /requests/view/:id/related
+func (h *RequestHandler) Related(c *fiber.Ctx) error {
+	opts := services.RelatedOptions{
+		Strategy: c.Query("strategy"),
+		Window:   time.Duration(c.QueryInt("window", 0)) * time.Second,
+	}
+	result, err := h.Service.RelatedRequests(c.UserContext(), c.Params("id"), opts)
+	if errors.Is(err, services.ErrInvalidRelatedStrategy) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if errors.Is(err, services.ErrRequestNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "not found"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 	}
@@ -40,9 +252,12 @@ func (h *RequestHandler) Analyze(c *fiber.Ctx) error {
 // FindByID handles GET /requests/view/:id
 func (h *RequestHandler) FindByID(c *fiber.Ctx) error {
 	id := c.Params("id")
-	result, err := h.Service.FindByID(id)
+	result, err := h.Service.FindByID(c.UserContext(), id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "not found"})
 	}
+	if c.QueryBool("pretty") {
+		return c.JSON(services.Pretty(result))
+	}
 	return c.JSON(result)
 }
@@ -0,0 +1,11 @@
+package monitoring
+
+import "github.com/aghiadodeh/go-monitoring/models"
+
+// SetIDGenerator replaces the function used to generate request and job
+// log IDs (default: UUIDv4). Values must be UUID formatted. Time-ordered
+// IDs (UUIDv7, or ULIDs in UUID form) improve index locality on busy
+// tables. Call it before Setup.
+func SetIDGenerator(fn func() string) {
+	models.SetIDGenerator(fn)
+}
@@ -0,0 +1,90 @@
+package logwriter
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDedupSize   = 10_000
+	defaultDedupWindow = time.Minute
+)
+
+// dedup remembers recently written entry keys (see dedupKey) in a
+// bounded LRU so the same request logged twice is only stored once.
+type dedup struct {
+	mu     sync.Mutex
+	size   int
+	window time.Duration
+	order  *list.List // front = most recently seen
+	items  map[string]*list.Element
+}
+
+type dedupItem struct {
+	key  string
+	seen time.Time
+}
+
+// dedupKey identifies entry: its ID when set, otherwise its trace ID
+// with method, URL and start time, since one trace spans many requests.
+// It returns "" when entry has neither an ID nor a trace ID.
+func dedupKey(entry *models.RequestLog) string {
+	if entry.ID != uuid.Nil {
+		return entry.ID.String()
+	}
+	if entry.TraceID == "" {
+		return ""
+	}
+	return entry.TraceID + " " + entry.Method + " " + entry.URL + " " +
+		strconv.FormatInt(entry.StartedAt.UnixNano(), 10)
+}
+
+func newDedup(size int, window time.Duration) *dedup {
+	if size <= 0 {
+		size = defaultDedupSize
+	}
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	return &dedup{
+		size:   size,
+		window: window,
+		order:  list.New(),
+		items:  make(map[string]*list.Element, size),
+	}
+}
+
+// duplicate reports whether key was seen within the window, and records
+// it otherwise. Empty keys are never duplicates.
+func (d *dedup) duplicate(key string) bool {
+	if key == "" {
+		return false
+	}
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.items[key]; ok {
+		item := el.Value.(*dedupItem)
+		if now.Sub(item.seen) < d.window {
+			return true
+		}
+		item.seen = now
+		d.order.MoveToFront(el)
+		return false
+	}
+
+	d.items[key] = d.order.PushFront(&dedupItem{key: key, seen: now})
+	for d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.items, oldest.Value.(*dedupItem).key)
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package logwriter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+	"github.com/google/uuid"
+)
+
+func TestDedupKeepsRequestsOfOneTrace(t *testing.T) {
+	d := newDedup(10, time.Minute)
+	start := time.Now()
+	entries := []models.RequestLog{
+		{TraceID: "t1", Method: "GET", URL: "/a", StartedAt: start},
+		{TraceID: "t1", Method: "GET", URL: "/b", StartedAt: start},
+		{TraceID: "t1", Method: "POST", URL: "/a", StartedAt: start},
+		{TraceID: "t1", Method: "GET", URL: "/a", StartedAt: start.Add(time.Millisecond)},
+	}
+	for i := range entries {
+		if d.duplicate(dedupKey(&entries[i])) {
+			t.Errorf("entry %d dropped as a duplicate", i)
+		}
+	}
+
+	again := entries[0]
+	if !d.duplicate(dedupKey(&again)) {
+		t.Error("same entry written twice was kept")
+	}
+}
+
+func TestDedupKeyPrefersID(t *testing.T) {
+	id := uuid.New()
+	a := models.RequestLog{ID: id, TraceID: "t1", URL: "/a"}
+	b := models.RequestLog{ID: id, TraceID: "t2", URL: "/b"}
+	if dedupKey(&a) != dedupKey(&b) {
+		t.Error("entries with the same ID have different keys")
+	}
+	if key := dedupKey(&models.RequestLog{URL: "/a"}); key != "" {
+		t.Errorf("entry without ID or trace ID has key %q", key)
+	}
+}
+
+func TestWriteDedup(t *testing.T) {
+	db, fake := newFakeDB(t)
+	w := New(db, Options{Dedup: true, FlushInterval: time.Hour})
+	defer w.Shutdown()
+
+	start := time.Now()
+	w.Write(models.RequestLog{TraceID: "t1", Method: "GET", URL: "http://h/a", Path: "/a", StartedAt: start})
+	w.Write(models.RequestLog{TraceID: "t1", Method: "GET", URL: "http://h/b", Path: "/b", StartedAt: start})
+	w.Write(models.RequestLog{TraceID: "t1", Method: "GET", URL: "http://h/a", Path: "/a", StartedAt: start})
+	w.Shutdown()
+
+	if got := len(fake.paths()); got != 2 {
+		t.Errorf("stored %d logs, want 2: %v", got, fake.paths())
+	}
+}
@@ -0,0 +1,45 @@
+package logwriter
+
+import (
+	"encoding/json"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+	"gorm.io/datatypes"
+)
+
+// IPEnricher returns extra data (e.g. country/city from a GeoIP lookup)
+// for a client IP. A nil or empty result leaves the entry unchanged.
+type IPEnricher func(ip string) map[string]any
+
+// SetIPEnricher installs fn to be called from the worker goroutine(s) for
+// every entry with a client IP. Its result is merged into the stored
+// request JSON under "geo". Passing nil disables enrichment.
+func (w *Writer) SetIPEnricher(fn IPEnricher) {
+	if fn == nil {
+		w.enricher.Store(nil)
+		return
+	}
+	w.enricher.Store(&fn)
+}
+
+// enrich applies the configured IPEnricher to entry. It runs in the
+// worker goroutine so slow lookups never delay the request handler.
+func (w *Writer) enrich(entry *models.RequestLog) {
+	fn := w.enricher.Load()
+	if fn == nil || entry.IP == "" {
+		return
+	}
+	geo := (*fn)(entry.IP)
+	if len(geo) == 0 {
+		return
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(entry.Request, &fields); err != nil || fields == nil {
+		fields = make(map[string]any)
+	}
+	fields["geo"] = geo
+	if out, err := json.Marshal(fields); err == nil {
+		entry.Request = datatypes.JSON(out)
+	}
+}
@@ -0,0 +1,49 @@
+package logwriter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// transient reports whether err means the database could not be reached
+// or was too busy, rather than that it refused the rows themselves, so
+// the same rows may succeed later. Errors that carry a SQLSTATE (e.g.
+// pgx) are classified by its class: 08 connection exception, 40
+// transaction rollback, 53 insufficient resources and 57 operator
+// intervention. Any other error is attributed to the rows.
+func transient(err error) bool {
+	switch {
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		code := state.SQLState()
+		for _, class := range []string{"08", "40", "53", "57"} {
+			if strings.HasPrefix(code, class) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package logwriter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// fakeDB is an in-memory gorm connection that records the paths of the
+// inserted rows. fail, when set, decides per statement whether the
+// INSERT of the given paths fails. conns, when set, is a connection pool
+// each INSERT must take a slot of, blocking while it is exhausted.
+type fakeDB struct {
+	mu       sync.Mutex
+	inserted []string
+	inserts  int
+	fail     func(paths []string) error
+
+	conns     chan struct{}
+	delay     time.Duration
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+// newFakeDB returns a gorm.DB backed by a fakeDB.
+func newFakeDB(t *testing.T) (*gorm.DB, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{}
+	db, err := gorm.Open(fakeDialector{pool: fake}, &gorm.Config{
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+// paths returns the inserted paths in insertion order.
+func (f *fakeDB) paths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.inserted...)
+}
+
+func (f *fakeDB) setFail(fail func(paths []string) error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if !strings.HasPrefix(query, "INSERT") {
+		return nil, errors.New("fakedb: unsupported statement: " + query)
+	}
+	var paths []string
+	for _, a := range args {
+		if s, ok := a.(string); ok && strings.HasPrefix(s, "/") {
+			paths = append(paths, s)
+		}
+	}
+
+	if f.conns != nil {
+		select {
+		case f.conns <- struct{}{}:
+			defer func() { <-f.conns }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	n := f.active.Add(1)
+	defer f.active.Add(-1)
+	for {
+		if m := f.maxActive.Load(); n <= m || f.maxActive.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inserts++
+	if f.fail != nil {
+		if err := f.fail(paths); err != nil {
+			return nil, err
+		}
+	}
+	f.inserted = append(f.inserted, paths...)
+	return fakeResult(len(paths)), nil
+}
+
+func (f *fakeDB) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("fakedb: prepare not supported")
+}
+
+func (f *fakeDB) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errors.New("fakedb: query not supported")
+}
+
+func (f *fakeDB) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+// fakeDialector plugs a fakeDB into gorm.
+type fakeDialector struct {
+	pool gorm.ConnPool
+}
+
+func (d fakeDialector) Name() string { return "fake" }
+
+func (d fakeDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	db.ConnPool = d.pool
+	return nil
+}
+
+func (d fakeDialector) Migrator(*gorm.DB) gorm.Migrator { return nil }
+
+func (d fakeDialector) DataTypeOf(*schema.Field) string { return "" }
+
+func (d fakeDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (d fakeDialector) BindVarTo(w clause.Writer, _ *gorm.Statement, _ any) {
+	_ = w.WriteByte('?')
+}
+
+func (d fakeDialector) QuoteTo(w clause.Writer, s string) {
+	_, _ = w.WriteString(`"` + s + `"`)
+}
+
+func (d fakeDialector) Explain(sql string, _ ...any) string { return sql }
+
+// sqlStateError mimics a driver error carrying a SQLSTATE code.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// logs returns one entry per path.
+func logs(paths ...string) []models.RequestLog {
+	out := make([]models.RequestLog, len(paths))
+	for i, p := range paths {
+		out[i] = models.RequestLog{Path: p, Method: "GET"}
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package logwriter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+)
+
+// defaultRecentWindow is the span covered by Writer.Recent.
+const defaultRecentWindow = time.Minute
+
+// RecentStats summarizes the entries processed by the writer during the
+// recent window, without touching the database.
+type RecentStats struct {
+	WindowSeconds     int     `json:"windowSeconds"`
+	Requests          int64   `json:"requests"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	ErrorRate         float64 `json:"errorRate"`  // 0–100
+	AvgLatency        float64 `json:"avgLatency"` // ms
+}
+
+// recentSlot accumulates the entries of one second.
+type recentSlot struct {
+	sec      int64
+	count    int64
+	errors   int64
+	duration float64
+}
+
+// recentCounter is a ring of per-second slots covering the window.
+type recentCounter struct {
+	mu    sync.Mutex
+	slots []recentSlot
+}
+
+func newRecentCounter(window time.Duration) *recentCounter {
+	if window <= 0 {
+		window = defaultRecentWindow
+	}
+	n := int(window / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	return &recentCounter{slots: make([]recentSlot, n)}
+}
+
+// add records entry in the slot for now.
+func (r *recentCounter) add(entry *models.RequestLog, now time.Time) {
+	sec := now.Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := &r.slots[int(sec%int64(len(r.slots)))]
+	if s.sec != sec {
+		*s = recentSlot{sec: sec}
+	}
+	s.count++
+	if !entry.Success {
+		s.errors++
+	}
+	s.duration += entry.Duration
+}
+
+// snapshot sums the slots that are still inside the window.
+func (r *recentCounter) snapshot(now time.Time) RecentStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.slots)
+	oldest := now.Unix() - int64(n) + 1
+	var count, errors int64
+	var duration float64
+	for _, s := range r.slots {
+		if s.sec >= oldest && s.count > 0 {
+			count += s.count
+			errors += s.errors
+			duration += s.duration
+		}
+	}
+
+	stats := RecentStats{
+		WindowSeconds:     n,
+		Requests:          count,
+		RequestsPerSecond: float64(count) / float64(n),
+	}
+	if count > 0 {
+		stats.ErrorRate = float64(errors) / float64(count) * 100
+		stats.AvgLatency = duration / float64(count)
+	}
+	return stats
+}
+
+// reset clears all slots.
+func (r *recentCounter) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.slots {
+		r.slots[i] = recentSlot{}
+	}
+}
+
+// Recent returns requests per second, error rate and average latency
+// over the last RecentWindow (default: 60s), computed from entries as
+// the workers process them. It never queries the database.
+func (w *Writer) Recent() RecentStats {
+	return w.recent.snapshot(time.Now())
+}
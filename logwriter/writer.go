@@ -1,12 +1,17 @@
 package logwriter
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aghiadodeh/go-monitoring/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Writer is a high-performance async batch writer for request logs.
@@ -16,12 +21,66 @@ type Writer struct {
 	db            *gorm.DB
 	ch            chan models.RequestLog
 	batchSize     int
+	maxBatchBytes int
 	flushInterval time.Duration
+	overflow      OverflowPolicy
+	blockTimeout  time.Duration
 	done          chan struct{}
+	abort         chan struct{}
+	abortOnce     sync.Once
 	wg            sync.WaitGroup
 	mu            sync.RWMutex
 	closed        bool
 	once          sync.Once
+	dropped       atomic.Int64
+	enricher      atomic.Pointer[IPEnricher]
+
+	// flushReqs has one channel per worker; Flush sends an ack channel
+	// on which the worker reports the result of writing its batch.
+	flushReqs []chan chan error
+
+	// onConflictDoNothing skips rows whose primary key already exists.
+	onConflictDoNothing bool
+
+	// chunkSize is the number of rows per INSERT within a batch; rejected
+	// counts rows dropped because the database refused their content.
+	chunkSize int
+	rejected  atomic.Int64
+
+	// recent counts processed entries for Recent.
+	recent *recentCounter
+
+	// dedup drops entries already written recently; nil = off.
+	dedup *dedup
+
+	// pendingSince holds, per worker, the UnixNano enqueue time of the
+	// oldest entry in its unflushed batch (0 = empty), for Lag.
+	pendingSince []atomic.Int64
+
+	// flushSem bounds concurrent INSERTs across workers; nil = unbounded.
+	flushSem chan struct{}
+
+	// fallback retains failed batches (oldest first) for retry.
+	fallbackCap int
+	fallbackMu  sync.Mutex
+	fallback    [][]models.RequestLog
+}
+
+// Stats is a point-in-time snapshot of the writer's internal state.
+type Stats struct {
+	Buffered int   `json:"buffered"` // entries waiting in the channel
+	Capacity int   `json:"capacity"` // channel capacity
+	Dropped  int64 `json:"dropped"`  // entries dropped or evicted because the buffer was full
+
+	FallbackDepth int `json:"fallbackDepth"` // failed batches waiting to be retried
+
+	// LagSeconds is the age of the oldest buffered entry not yet stored.
+	LagSeconds float64 `json:"lagSeconds"`
+
+	// Rejected counts entries dropped because the database refused their
+	// content (e.g. invalid JSON or a constraint violation) rather than
+	// failing to store them.
+	Rejected int64 `json:"rejected"`
 }
 
 // Options configures the Writer.
@@ -30,6 +89,48 @@ type Options struct {
 	BatchSize     int           // records per INSERT        (default: 100)
 	FlushInterval time.Duration // max idle time before flush (default: 5 s)
 	Workers       int           // parallel writer goroutines (default: 1)
+	MaxBatchBytes int           // approx. payload bytes per INSERT (default: 0 = unlimited)
+
+	OverflowPolicy OverflowPolicy // behaviour when the buffer is full (default: OverflowDrop)
+	BlockTimeout   time.Duration  // max wait for OverflowBlock (default: 100 ms)
+
+	// FallbackCapacity is the number of failed batches kept in memory and
+	// retried after the next successful flush (default: 0 = disabled).
+	// When full, the oldest batch is discarded.
+	FallbackCapacity int
+
+	// MaxDBConns caps how many workers may write to the database at once,
+	// so Workers > 1 cannot exhaust a small connection pool (default: 0 =
+	// one connection per worker). Set it to 1 to serialize flushes; keep
+	// it below the pool's MaxOpenConns to leave room for the app.
+	MaxDBConns int
+
+	// Dedup drops an entry when the same entry was written within
+	// DedupWindow (default: 1m), e.g. an entry passed to LogRequest again
+	// by code that retries on error. Entries are identified by ID, or
+	// by TraceID, method, URL and StartedAt when ID is unset, so requests
+	// sharing a trace are all kept. Keys are kept in an LRU of DedupSize
+	// entries (default: 10 000). Off by default since it adds a map
+	// lookup under a lock to every Write.
+	Dedup       bool
+	DedupWindow time.Duration
+	DedupSize   int
+
+	// OnConflictDoNothing inserts batches with ON CONFLICT DO NOTHING (or
+	// the dialect's equivalent), so a row whose ID already exists is
+	// skipped instead of failing the whole batch (default: false).
+	OnConflictDoNothing bool
+
+	// RecentWindow is the span summarized by Recent (default: 60s).
+	RecentWindow time.Duration
+
+	// ChunkSize splits each batch into INSERTs of at most this many rows
+	// (default: 50). When the database refuses a chunk for its content,
+	// its rows are retried one by one and only the offending ones are
+	// dropped, so a single malformed entry cannot lose the whole batch.
+	// Connection errors stop the flush and keep the remaining rows for
+	// the fallback buffer.
+	ChunkSize int
 }
 
 // New creates a Writer and starts its background worker(s).
@@ -46,26 +147,71 @@ func New(db *gorm.DB, opts Options) *Writer {
 	if opts.Workers <= 0 {
 		opts.Workers = 1
 	}
+	if opts.OverflowPolicy == "" {
+		opts.OverflowPolicy = OverflowDrop
+	}
+	if opts.BlockTimeout <= 0 {
+		opts.BlockTimeout = 100 * time.Millisecond
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 50
+	}
 
 	w := &Writer{
 		db:            db,
 		ch:            make(chan models.RequestLog, opts.BufferSize),
 		batchSize:     opts.BatchSize,
+		maxBatchBytes: opts.MaxBatchBytes,
 		flushInterval: opts.FlushInterval,
+		overflow:      opts.OverflowPolicy,
+		blockTimeout:  opts.BlockTimeout,
 		done:          make(chan struct{}),
+		abort:         make(chan struct{}),
+		fallbackCap:   opts.FallbackCapacity,
+		recent:        newRecentCounter(opts.RecentWindow),
+
+		onConflictDoNothing: opts.OnConflictDoNothing,
+		chunkSize:           opts.ChunkSize,
 	}
 
+	if opts.Dedup {
+		w.dedup = newDedup(opts.DedupSize, opts.DedupWindow)
+	}
+	if opts.MaxDBConns > 0 && opts.MaxDBConns < opts.Workers {
+		w.flushSem = make(chan struct{}, opts.MaxDBConns)
+	}
+
+	w.pendingSince = make([]atomic.Int64, opts.Workers)
 	for i := 0; i < opts.Workers; i++ {
+		req := make(chan chan error)
+		w.flushReqs = append(w.flushReqs, req)
 		w.wg.Add(1)
-		go w.worker()
+		go w.worker(req, &w.pendingSince[i])
 	}
 
 	return w
 }
 
-// Write enqueues a log entry. It never blocks the caller: if the
-// buffer is full or the writer has been shut down, the entry is
-// silently dropped.
+// ErrFallbackPending is returned by Flush when logs that could not be
+// stored are still held in the fallback buffer for retry.
+var ErrFallbackPending = errors.New("logwriter: logs pending in the fallback buffer")
+
+// OverflowPolicy controls what Write does when the buffer is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDrop discards the new entry (default).
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowBlock waits up to BlockTimeout for room, then discards.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest evicts the oldest buffered entry to make room.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+)
+
+// Write enqueues a log entry. With the default OverflowDrop policy it
+// never blocks the caller: if the buffer is full or the writer has been
+// shut down, the entry is dropped. OverflowBlock may block for up to
+// BlockTimeout.
 func (w *Writer) Write(entry models.RequestLog) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
@@ -73,27 +219,109 @@ func (w *Writer) Write(entry models.RequestLog) {
 	if w.closed {
 		return
 	}
+	if w.dedup != nil && w.dedup.duplicate(dedupKey(&entry)) {
+		return
+	}
+	entry.EnqueuedAt = time.Now()
 
 	select {
 	case w.ch <- entry:
+		return
 	default:
-		// Buffer full – drop to protect request latency.
-		log.Println("[go-monitoring] warning: log buffer full, dropping entry")
 	}
+
+	switch w.overflow {
+	case OverflowBlock:
+		timer := time.NewTimer(w.blockTimeout)
+		defer timer.Stop()
+		select {
+		case w.ch <- entry:
+			return
+		case <-timer.C:
+		}
+
+	case OverflowDropOldest:
+		select {
+		case <-w.ch:
+			w.dropped.Add(1)
+		default:
+		}
+		select {
+		case w.ch <- entry:
+			return
+		default:
+		}
+	}
+
+	// Buffer full – drop to protect request latency.
+	w.dropped.Add(1)
+	log.Println("[go-monitoring] warning: log buffer full, dropping entry")
 }
 
 // Shutdown closes the channel and waits for all pending entries
 // to be flushed. It is safe to call multiple times.
 func (w *Writer) Shutdown() {
+	_ = w.ShutdownContext(context.Background())
+}
+
+// ShutdownContext closes the channel and waits for all pending entries
+// to be flushed, or until ctx is done. On timeout the workers are told
+// to stop without draining the remaining buffer, and ctx.Err() is
+// returned. A flush already in progress is not interrupted.
+// It is safe to call multiple times.
+func (w *Writer) ShutdownContext(ctx context.Context) error {
 	w.once.Do(func() {
 		w.mu.Lock()
 		w.closed = true
 		w.mu.Unlock()
 
 		close(w.ch)
-		w.wg.Wait()
-		close(w.done)
+		go func() {
+			w.wg.Wait()
+			w.recent.reset()
+			close(w.done)
+		}()
 	})
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.abortOnce.Do(func() { close(w.abort) })
+		log.Printf("[go-monitoring] warning: shutdown timed out, abandoning %d buffered log(s)\n", len(w.ch))
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the buffer usage and drop counter.
+func (w *Writer) Stats() Stats {
+	return Stats{
+		Buffered: len(w.ch),
+		Capacity: cap(w.ch),
+		Dropped:  w.dropped.Load(),
+
+		FallbackDepth: w.fallbackDepth(),
+		LagSeconds:    w.Lag().Seconds(),
+		Rejected:      w.rejected.Load(),
+	}
+}
+
+// Lag returns how long the oldest entry accepted by Write has been
+// waiting to be stored, or 0 when nothing is pending. Entries still in
+// the channel are newer than those in the workers' batches, so only the
+// batches and the retained fallback batches are checked; the latter keep
+// the lag growing during a database outage.
+func (w *Writer) Lag() time.Duration {
+	oldest := w.fallbackOldest()
+	for i := range w.pendingSince {
+		if t := w.pendingSince[i].Load(); t > 0 && (oldest == 0 || t < oldest) {
+			oldest = t
+		}
+	}
+	if oldest == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, oldest))
 }
 
 // Done returns a channel that is closed after Shutdown completes.
@@ -101,43 +329,276 @@ func (w *Writer) Done() <-chan struct{} {
 	return w.done
 }
 
+// Flush writes everything buffered so far: each worker drains the
+// entries currently waiting in the channel, flushes its batch and
+// reports back. It blocks until all workers are done or ctx expires, and
+// is meant for tests asserting a log was persisted and for checkpoints
+// in cron jobs. It returns the errors of failed inserts and wraps
+// ErrFallbackPending when failed logs are still waiting to be retried,
+// so a nil error means everything buffered was stored (rows the
+// database refused are dropped, see Stats.Rejected). After Shutdown it
+// returns nil immediately.
+func (w *Writer) Flush(ctx context.Context) error {
+	acks := make([]chan error, 0, len(w.flushReqs))
+	for _, req := range w.flushReqs {
+		// Buffered so the worker never blocks on an abandoned Flush.
+		ack := make(chan error, 1)
+		select {
+		case req <- ack:
+			acks = append(acks, ack)
+		case <-w.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	var errs []error
+	for _, ack := range acks {
+		select {
+		case err := <-ack:
+			errs = append(errs, err)
+		case <-w.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if depth := w.fallbackDepth(); depth > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d batch(es)", ErrFallbackPending, depth))
+	}
+	return errors.Join(errs...)
+}
+
 // worker reads from the channel, accumulates a batch, and flushes
-// either when the batch is full or when the flush interval fires.
-func (w *Writer) worker() {
+// either when the batch is full (by count or approximate byte size),
+// when the flush interval fires, or when Flush asks it to.
+func (w *Writer) worker(flushReq <-chan chan error, pendingSince *atomic.Int64) {
 	defer w.wg.Done()
 
 	batch := make([]models.RequestLog, 0, w.batchSize)
+	batchBytes := 0
 	ticker := time.NewTicker(w.flushInterval)
 	defer ticker.Stop()
 
+	flushBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := w.flush(batch)
+		batch = batch[:0]
+		batchBytes = 0
+		pendingSince.Store(0)
+		return err
+	}
+	add := func(entry models.RequestLog) error {
+		w.enrich(&entry)
+		w.recent.add(&entry, time.Now())
+		if len(batch) == 0 && !entry.EnqueuedAt.IsZero() {
+			pendingSince.Store(entry.EnqueuedAt.UnixNano())
+		}
+		batch = append(batch, entry)
+		batchBytes += entrySize(entry)
+		if len(batch) >= w.batchSize || (w.maxBatchBytes > 0 && batchBytes >= w.maxBatchBytes) {
+			return flushBatch()
+		}
+		return nil
+	}
+
 	for {
 		select {
 		case entry, ok := <-w.ch:
 			if !ok {
 				// Channel closed – flush remaining and exit.
-				if len(batch) > 0 {
-					w.flush(batch)
-				}
+				flushBatch()
 				return
 			}
-			batch = append(batch, entry)
-			if len(batch) >= w.batchSize {
-				w.flush(batch)
-				batch = batch[:0]
-			}
+			add(entry)
 
 		case <-ticker.C:
-			if len(batch) > 0 {
-				w.flush(batch)
-				batch = batch[:0]
+			flushBatch()
+
+		case ack := <-flushReq:
+			// Take what is buffered right now; other workers drain
+			// concurrently, so stop as soon as the channel is empty.
+			// Batches filled mid-drain flush early, so every error is
+			// collected for the ack, not just the last one.
+			var errs []error
+		drain:
+			for n := len(w.ch); n > 0; n-- {
+				select {
+				case entry, ok := <-w.ch:
+					if !ok {
+						break drain
+					}
+					errs = append(errs, add(entry))
+				default:
+					break drain
+				}
+			}
+			errs = append(errs, flushBatch())
+			ack <- errors.Join(errs...)
+
+		case <-w.abort:
+			return
+		}
+	}
+}
+
+// entrySize approximates the serialized size of a log entry. It only
+// counts variable-length fields, which dominate the statement size.
+func entrySize(e models.RequestLog) int {
+	return len(e.Key) + len(e.Path) + len(e.URL) + len(e.Method) +
+		len(e.User) + len(e.Request) + len(e.Response) + len(e.ResponseHeaders)
+}
+
+// flush inserts the batch (see insertRows) and returns the error that
+// stopped it. On failure the rows not stored are retained in the
+// fallback buffer (if enabled); on success any retained batches are
+// retried.
+func (w *Writer) flush(batch []models.RequestLog) error {
+	if w.flushSem != nil {
+		select {
+		case w.flushSem <- struct{}{}:
+			defer func() { <-w.flushSem }()
+		case <-w.abort:
+			// Shutdown timed out while waiting for a connection slot.
+			return errors.New("logwriter: shutdown timed out")
+		}
+	}
+
+	if rest, err := w.insertRows(batch); err != nil {
+		log.Printf("[go-monitoring] error flushing %d log(s): %v\n", len(rest), err)
+		w.retain(rest)
+		return err
+	}
+	w.retryFallback()
+	return nil
+}
+
+// insertRows writes rows in INSERTs of chunkSize rows. A chunk the
+// database refuses for its content is retried row by row and the
+// offending rows are dropped. On a transient error it stops and returns
+// the rows not stored yet with the error.
+func (w *Writer) insertRows(rows []models.RequestLog) ([]models.RequestLog, error) {
+	// Each chunk is a single statement, so a failed chunk stored nothing
+	// and can be retried row by row without duplicates.
+	for start := 0; start < len(rows); start += w.chunkSize {
+		chunk := rows[start:min(start+w.chunkSize, len(rows))]
+		err := w.insert(chunk)
+		if err == nil {
+			continue
+		}
+		if transient(err) {
+			return rows[start:], err
+		}
+		if n, err := w.insertEach(chunk, err); err != nil {
+			return rows[start+n:], err
+		}
+	}
+	return nil, nil
+}
+
+// insertEach inserts the rows of a chunk that failed with chunkErr one
+// at a time, dropping and logging the rows the database refuses. On a
+// transient error it stops and returns the number of rows handled.
+func (w *Writer) insertEach(chunk []models.RequestLog, chunkErr error) (int, error) {
+	for i := range chunk {
+		err := chunkErr
+		if len(chunk) > 1 {
+			err = w.insert(chunk[i : i+1])
+		}
+		if err == nil {
+			continue
+		}
+		if transient(err) {
+			return i, err
+		}
+		log.Printf("[go-monitoring] dropping log %s %s: %v\n", chunk[i].Method, chunk[i].Path, err)
+		w.rejected.Add(1)
+	}
+	return len(chunk), nil
+}
+
+// insert writes batch with a single multi-row INSERT.
+func (w *Writer) insert(batch []models.RequestLog) error {
+	db := w.db
+	if w.onConflictDoNothing {
+		db = db.Clauses(clause.OnConflict{DoNothing: true})
+	}
+	return db.Create(&batch).Error
+}
+
+// retain stores a copy of batch in the fallback ring buffer, evicting
+// the oldest batch when the buffer is full.
+func (w *Writer) retain(batch []models.RequestLog) {
+	if w.fallbackCap <= 0 {
+		return
+	}
+
+	// The worker reuses its batch slice, so keep an independent copy.
+	cp := make([]models.RequestLog, len(batch))
+	copy(cp, batch)
+
+	w.fallbackMu.Lock()
+	defer w.fallbackMu.Unlock()
+
+	if len(w.fallback) >= w.fallbackCap {
+		log.Printf("[go-monitoring] warning: fallback buffer full, discarding %d log(s)\n", len(w.fallback[0]))
+		w.fallback = w.fallback[1:]
+	}
+	w.fallback = append(w.fallback, cp)
+}
+
+// retryFallback re-attempts every retained batch, dropping rows the
+// database refuses. On a transient error the rows not stored yet are put
+// back in the buffer.
+func (w *Writer) retryFallback() {
+	if w.fallbackCap <= 0 {
+		return
+	}
+
+	w.fallbackMu.Lock()
+	pending := w.fallback
+	w.fallback = nil
+	w.fallbackMu.Unlock()
+
+	for i, batch := range pending {
+		if rest, err := w.insertRows(batch); err != nil {
+			log.Printf("[go-monitoring] error retrying %d log(s): %v\n", len(rest), err)
+			pending[i] = rest
+			w.fallbackMu.Lock()
+			w.fallback = append(pending[i:], w.fallback...)
+			if over := len(w.fallback) - w.fallbackCap; over > 0 {
+				w.fallback = w.fallback[over:]
 			}
+			w.fallbackMu.Unlock()
+			return
 		}
 	}
 }
 
-// flush performs a single multi-row INSERT for the batch.
-func (w *Writer) flush(batch []models.RequestLog) {
-	if err := w.db.Create(&batch).Error; err != nil {
-		log.Printf("[go-monitoring] error flushing %d log(s): %v\n", len(batch), err)
+// fallbackOldest returns the UnixNano enqueue time of the oldest retained
+// entry, or 0 when the fallback buffer is empty.
+func (w *Writer) fallbackOldest() int64 {
+	w.fallbackMu.Lock()
+	defer w.fallbackMu.Unlock()
+
+	var oldest int64
+	for _, batch := range w.fallback {
+		for i := range batch {
+			if batch[i].EnqueuedAt.IsZero() {
+				continue
+			}
+			if t := batch[i].EnqueuedAt.UnixNano(); oldest == 0 || t < oldest {
+				oldest = t
+			}
+		}
 	}
+	return oldest
+}
+
+func (w *Writer) fallbackDepth() int {
+	w.fallbackMu.Lock()
+	defer w.fallbackMu.Unlock()
+	return len(w.fallback)
 }
@@ -0,0 +1,209 @@
+package logwriter
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{sqlStateError("08006"), true},
+		{sqlStateError("53300"), true},
+		{sqlStateError("23505"), false},
+		{sqlStateError("22P02"), false},
+		{errors.New("invalid input syntax for type json"), false},
+	}
+	for _, tt := range tests {
+		if got := transient(tt.err); got != tt.want {
+			t.Errorf("transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFlushDropsRejectedRows(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.setFail(func(paths []string) error {
+		if slices.Contains(paths, "/bad") {
+			return sqlStateError("22P02")
+		}
+		return nil
+	})
+	w := &Writer{db: db, chunkSize: 2, fallbackCap: 4}
+
+	w.flush(logs("/a", "/bad", "/c", "/d", "/bad"))
+
+	if got, want := fake.paths(), []string{"/a", "/c", "/d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inserted %v, want %v", got, want)
+	}
+	if got := w.rejected.Load(); got != 2 {
+		t.Errorf("rejected = %d, want 2", got)
+	}
+	if got := w.fallbackDepth(); got != 0 {
+		t.Errorf("fallback depth = %d, want 0", got)
+	}
+}
+
+func TestFlushStopsOnConnectionError(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.setFail(func([]string) error { return driver.ErrBadConn })
+	w := &Writer{db: db, chunkSize: 2, fallbackCap: 4}
+
+	w.flush(logs("/a", "/b", "/c", "/d", "/e"))
+
+	if fake.inserts != 1 {
+		t.Errorf("inserts = %d, want 1 (no chunking or row isolation after a connection error)", fake.inserts)
+	}
+	if got := w.rejected.Load(); got != 0 {
+		t.Errorf("rejected = %d, want 0", got)
+	}
+	if len(w.fallback) != 1 || len(w.fallback[0]) != 5 {
+		t.Fatalf("fallback = %v, want one batch of 5", w.fallback)
+	}
+}
+
+func TestFlushKeepsRowsAfterMidBatchOutage(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.setFail(func(paths []string) error {
+		if slices.Contains(paths, "/c") {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	w := &Writer{db: db, chunkSize: 2, fallbackCap: 4}
+
+	w.flush(logs("/a", "/b", "/c", "/d", "/e"))
+
+	if len(w.fallback) != 1 {
+		t.Fatalf("fallback depth = %d, want 1", len(w.fallback))
+	}
+	var kept []string
+	for _, e := range w.fallback[0] {
+		kept = append(kept, e.Path)
+	}
+	if want := []string{"/c", "/d", "/e"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+
+	// Once the database is back, the retained rows are stored.
+	fake.setFail(nil)
+	w.flush(logs("/f"))
+	if got, want := fake.paths(), []string{"/a", "/b", "/f", "/c", "/d", "/e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inserted %v, want %v", got, want)
+	}
+	if got := w.fallbackDepth(); got != 0 {
+		t.Errorf("fallback depth = %d, want 0", got)
+	}
+}
+
+func TestRetryFallbackDropsPoisonRows(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.setFail(func([]string) error { return driver.ErrBadConn })
+	w := &Writer{db: db, chunkSize: 10, fallbackCap: 4}
+	w.flush(logs("/a", "/bad"))
+	w.flush(logs("/c"))
+
+	// The database recovers but refuses /bad for its content: it must
+	// not keep the retained batches from being stored.
+	fake.setFail(func(paths []string) error {
+		if slices.Contains(paths, "/bad") {
+			return sqlStateError("23514")
+		}
+		return nil
+	})
+	w.flush(logs("/d"))
+
+	if got, want := fake.paths(), []string{"/d", "/a", "/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inserted %v, want %v", got, want)
+	}
+	if got := w.fallbackDepth(); got != 0 {
+		t.Errorf("fallback depth = %d, want 0", got)
+	}
+	if got := w.rejected.Load(); got != 1 {
+		t.Errorf("rejected = %d, want 1", got)
+	}
+}
+
+func TestFlushRejectsSingleRow(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.setFail(func([]string) error { return sqlStateError("22P02") })
+	w := &Writer{db: db, chunkSize: 50, fallbackCap: 4}
+
+	w.flush(logs("/bad"))
+
+	if got := w.rejected.Load(); got != 1 {
+		t.Errorf("rejected = %d, want 1", got)
+	}
+	if got := w.fallbackDepth(); got != 0 {
+		t.Errorf("fallback depth = %d, want 0", got)
+	}
+}
+
+func TestFlushReportsErrors(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.setFail(func([]string) error { return driver.ErrBadConn })
+	w := New(db, Options{Workers: 2, FallbackCapacity: 4, FlushInterval: time.Hour})
+	defer w.Shutdown()
+
+	w.Write(logs("/a")[0])
+	err := w.Flush(context.Background())
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("Flush() = %v, want driver.ErrBadConn", err)
+	}
+	if !errors.Is(err, ErrFallbackPending) {
+		t.Errorf("Flush() = %v, want ErrFallbackPending", err)
+	}
+
+	// Nothing new to write, but the retained batch is still pending.
+	if err := w.Flush(context.Background()); !errors.Is(err, ErrFallbackPending) {
+		t.Errorf("second Flush() = %v, want ErrFallbackPending", err)
+	}
+
+	fake.setFail(nil)
+	w.Write(logs("/b")[0])
+	if err := w.Flush(context.Background()); err != nil {
+		t.Errorf("Flush() after recovery = %v, want nil", err)
+	}
+	if got := len(fake.paths()); got != 2 {
+		t.Errorf("stored %d logs, want 2", got)
+	}
+}
+
+func TestMaxDBConnsWithExhaustedPool(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.conns = make(chan struct{}, 2)
+	fake.delay = time.Millisecond
+	// The application holds one of the two connections throughout.
+	fake.conns <- struct{}{}
+
+	w := New(db, Options{Workers: 4, MaxDBConns: 1, BatchSize: 5, FlushInterval: time.Hour})
+	for i := range 200 {
+		w.Write(logs(fmt.Sprintf("/r%d", i))[0])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() = %v, want nil (deadlock?)", err)
+	}
+	if err := w.ShutdownContext(ctx); err != nil {
+		t.Fatalf("ShutdownContext() = %v", err)
+	}
+	if got := len(fake.paths()); got != 200 {
+		t.Errorf("stored %d logs, want 200", got)
+	}
+	if got := fake.maxActive.Load(); got > 1 {
+		t.Errorf("%d concurrent inserts, want at most MaxDBConns = 1", got)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// indexBodyFields extracts the given top-level keys from a JSON object
+// body. Values are stored as strings (JSON text for non-strings) so the
+// field filter compares them the same way on every database. It returns
+// nil when the body is not a JSON object or none of the keys are present.
+func indexBodyFields(body []byte, keys []string) map[string]string {
+	if len(keys) == 0 || !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil
+	}
+
+	var out map[string]string
+	for _, k := range keys {
+		raw, ok := obj[k]
+		if !ok {
+			continue
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			// Numbers, booleans, objects: keep the compact JSON text.
+			var buf bytes.Buffer
+			if json.Compact(&buf, raw) != nil {
+				continue
+			}
+			v = buf.String()
+		}
+		if out == nil {
+			out = make(map[string]string, len(keys))
+		}
+		out[k] = v
+	}
+	return out
+}
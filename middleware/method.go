@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OtherMethod is stored in place of unknown methods when
+// MiddlewareConfig.RestrictMethods is set.
+const OtherMethod = "OTHER"
+
+// knownMethods are the standard HTTP methods kept by RestrictMethods.
+var knownMethods = map[string]bool{
+	fiber.MethodGet:     true,
+	fiber.MethodHead:    true,
+	fiber.MethodPost:    true,
+	fiber.MethodPut:     true,
+	fiber.MethodPatch:   true,
+	fiber.MethodDelete:  true,
+	fiber.MethodConnect: true,
+	fiber.MethodOptions: true,
+	fiber.MethodTrace:   true,
+}
+
+// normalizeMethod upper-cases method and, when restrict is set, maps
+// anything that is not a standard HTTP method to OtherMethod.
+func normalizeMethod(method string, restrict bool) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if restrict && !knownMethods[method] {
+		return OtherMethod
+	}
+	return method
+}
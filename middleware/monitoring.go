@@ -1,35 +1,150 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/aghiadodeh/go-monitoring/core"
 	"github.com/aghiadodeh/go-monitoring/logwriter"
-	"github.com/aghiadodeh/go-monitoring/models"
+	"github.com/aghiadodeh/go-monitoring/otel"
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/datatypes"
+	"github.com/google/uuid"
 )
 
 // MiddlewareConfig holds options for the monitoring middleware.
 type MiddlewareConfig struct {
+	// Recorder receives every captured request. When nil, one is built
+	// from Writer, Spans and the compression options.
+	Recorder *core.Recorder
+
 	Writer          *logwriter.Writer
 	SkipPaths       []string // URL prefixes to skip (default: "/api/monitoring")
 	UserContextKey  string   // c.Locals key for user (default: "user")
 	MaxBodySize     int      // max body bytes to capture (-1 = unlimited, default: 64KB)
+	MaxReqBodySize  int      // request body cap (-1 = unlimited, 0 = use MaxBodySize)
+	MaxRespBodySize int      // response body cap (-1 = unlimited, 0 = use MaxBodySize)
 	CaptureReqBody  bool
 	CaptureRespBody bool
+
+	// CaptureReqBodyOnErrorOnly and CaptureRespBodyOnErrorOnly keep the
+	// respective body only for unsuccessful requests; successful ones
+	// store null. The body is copied after the status is known. They
+	// have no effect unless CaptureReqBody / CaptureRespBody is set.
+	CaptureReqBodyOnErrorOnly  bool
+	CaptureRespBodyOnErrorOnly bool
+
+	// CaptureBodyOverDurationMs, when positive, keeps bodies only for
+	// requests slower than this many milliseconds, for debugging slow
+	// endpoints. Combined with the OnErrorOnly flags a body is kept when
+	// the request failed or was slow.
+	CaptureBodyOverDurationMs float64
+
+	// IndexReqBodyFields lists top-level JSON request body keys (e.g.
+	// "orderId", "email") copied into the searchable indexed_fields
+	// column, independent of CaptureReqBody.
+	IndexReqBodyFields []string
+
+	// SkipHeader names a request header (e.g. "X-Monitoring-Skip") that,
+	// set to 1 or true, excludes that request from logging, e.g. for
+	// health checks or load tests. ForceHeader (e.g. "X-Monitoring-Force")
+	// captures a request even on a SkipPaths prefix. Both are disabled
+	// when empty; any client can send them, so only enable SkipHeader
+	// where hiding a request from monitoring is acceptable.
+	SkipHeader  string
+	ForceHeader string
+
+	// CaptureStackTraces recovers panics in downstream handlers, answers
+	// them through the app's ErrorHandler (500 by default) and stores the
+	// Go stack trace with the log under response.stack. Stacks reveal
+	// internals, so it is off by default. A recover middleware registered
+	// after this one sees the panic first and hides the stack.
+	CaptureStackTraces bool
+
+	// RestrictMethods stores any method other than the standard HTTP
+	// methods as OtherMethod, so custom verbs do not pollute filters.
+	// Methods are always stored upper-case.
+	RestrictMethods bool
+
+	// SkipBodyContentTypes lists content types whose bodies are replaced
+	// by a {"_skipped":"content-type"} marker. Entries match by prefix
+	// or with a trailing wildcard ("image/*"), case-insensitively.
+	// Default: multipart/form-data, application/octet-stream, image/*.
+	SkipBodyContentTypes []string
+
+	// CompressBodies gzip-compresses captured bodies larger than
+	// CompressThreshold bytes (default: 4KB) before storing them.
+	CompressBodies    bool
+	CompressThreshold int
+
+	// TraceHeader is the request header carrying the correlation ID.
+	// When empty, X-Request-Id is used, falling back to the trace-id part
+	// of a W3C traceparent header. A UUID is generated when neither is
+	// present, and the ID is echoed back in the response header.
+	TraceHeader string
+
+	// OperationNameExtractor returns a logical operation name (e.g. a
+	// GraphQL operationName) that replaces the route path for grouping.
+	// An empty result keeps the route path. Defaults to
+	// GraphQLOperationName for requests to GraphQLPath, if set.
+	OperationNameExtractor func(*fiber.Ctx) string
+
+	// GraphQLPath is the path of the GraphQL endpoint, e.g. "/graphql".
+	// POSTs to it are grouped by operationName. Other routes never are,
+	// so clients cannot rename them (default: "" = disabled).
+	GraphQLPath string
+
+	// KeyFunc returns the key stored with each log, e.g. a service name
+	// or API version. When nil or empty, core.DefaultKey is used.
+	KeyFunc func(*fiber.Ctx) string
+
+	// IPEnricher, when set, is installed on Writer and called from its
+	// worker goroutine with each client IP. The result (e.g. a GeoIP
+	// country/city) is stored in the request JSON under "geo".
+	IPEnricher logwriter.IPEnricher
+
+	// SuccessFunc decides whether a response counts as successful.
+	// When nil, any status below 400 is a success.
+	SuccessFunc func(status int, c *fiber.Ctx) bool
+
+	// ExposeBufferHeader adds an X-Monitoring-Buffer: used/cap response
+	// header to monitored requests. Off by default since it reveals
+	// internal state to clients.
+	ExposeBufferHeader bool
+
+	// Spans, when non-nil, additionally receives one span per captured
+	// request. Export is non-blocking.
+	Spans *otel.Exporter
+}
+
+// DefaultSkipBodyContentTypes are the content types whose bodies are
+// not captured unless MiddlewareConfig.SkipBodyContentTypes overrides them.
+var DefaultSkipBodyContentTypes = []string{
+	"multipart/form-data",
+	"application/octet-stream",
+	"image/*",
 }
 
+// skippedBodyMarker replaces bodies whose content type is skipped.
+var skippedBodyMarker = json.RawMessage(`{"_skipped":"content-type"}`)
+
 // uuidRe matches standard UUIDs (v4 and similar).
 var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 
+// traceIDRe matches client-supplied trace IDs that are safe to store and
+// echo back; anything else is replaced by a generated UUID.
+var traceIDRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
 // numericRe matches purely numeric path segments.
 var numericRe = regexp.MustCompile(`^\d+$`)
 
 // New returns a Fiber middleware that captures request/response data
-// and sends it to the async log writer. All heavy work (DB write) runs
+// and hands it to a core.Recorder. All heavy work (DB write) runs
 // in an isolated goroutine via the Writer — the HTTP response is never
 // blocked by monitoring.
 func New(cfg MiddlewareConfig) fiber.Handler {
@@ -39,34 +154,82 @@ func New(cfg MiddlewareConfig) fiber.Handler {
 	if cfg.MaxBodySize == 0 {
 		cfg.MaxBodySize = 64 * 1024
 	}
+	if cfg.MaxReqBodySize == 0 {
+		cfg.MaxReqBodySize = cfg.MaxBodySize
+	}
+	if cfg.MaxRespBodySize == 0 {
+		cfg.MaxRespBodySize = cfg.MaxBodySize
+	}
+	if cfg.SkipBodyContentTypes == nil {
+		cfg.SkipBodyContentTypes = DefaultSkipBodyContentTypes
+	}
+	if cfg.OperationNameExtractor == nil && cfg.GraphQLPath != "" {
+		cfg.OperationNameExtractor = graphQLOperationAt(cfg.GraphQLPath)
+	}
+	if cfg.IPEnricher != nil && cfg.Writer != nil {
+		cfg.Writer.SetIPEnricher(cfg.IPEnricher)
+	}
+	if cfg.Recorder == nil {
+		cfg.Recorder = &core.Recorder{
+			Writer:            cfg.Writer,
+			Spans:             cfg.Spans,
+			CompressBodies:    cfg.CompressBodies,
+			CompressThreshold: cfg.CompressThreshold,
+		}
+	}
 
 	return func(c *fiber.Ctx) error {
-		// Check if this path should be skipped.
+		// Check if this request should be skipped.
 		path := c.Path()
-		for _, sp := range cfg.SkipPaths {
-			if strings.HasPrefix(path, sp) {
-				return c.Next()
+		if headerEnabled(c, cfg.SkipHeader) {
+			return c.Next()
+		}
+		if !headerEnabled(c, cfg.ForceHeader) {
+			for _, sp := range cfg.SkipPaths {
+				if strings.HasPrefix(path, sp) {
+					return c.Next()
+				}
 			}
 		}
 
 		// --- Capture request data (synchronous – before handler) ---
-		reqIP := c.IP()
-		reqMethod := c.Method()
+		in := core.RequestLogInput{
+			IP:             c.IP(),
+			Method:         normalizeMethod(c.Method(), cfg.RestrictMethods),
+			RequestHeaders: captureRequestHeaders(c),
+			Params:         c.AllParams(),
+			Queries:        c.Queries(),
+		}
 		reqOriginalURL := c.OriginalURL()
 
-		reqHeaders := captureRequestHeaders(c)
-		reqParams := c.AllParams()
-		reqQueries := c.Queries()
+		in.TraceID = cfg.traceID(c)
+		c.Locals(traceIDLocalsKey, in.TraceID)
+		c.Set(cfg.traceResponseHeader(), in.TraceID)
+
+		var operation string
+		if cfg.OperationNameExtractor != nil {
+			operation = cfg.OperationNameExtractor(c)
+		}
+		in.IndexedFields = indexBodyFields(c.Body(), cfg.IndexReqBodyFields)
+
+		// Selective capture needs the outcome, so the copy is deferred.
+		deferReqBody := cfg.CaptureReqBodyOnErrorOnly || cfg.CaptureBodyOverDurationMs > 0
+		deferRespBody := cfg.CaptureRespBodyOnErrorOnly || cfg.CaptureBodyOverDurationMs > 0
 
-		var reqBody json.RawMessage
-		if cfg.CaptureReqBody {
-			reqBody = copyBytes(c.Body(), cfg.MaxBodySize)
+		if cfg.CaptureReqBody && !deferReqBody {
+			in.RequestBody = cfg.captureBody(c.Get(fiber.HeaderContentType), c.Body(), cfg.MaxReqBodySize)
 		}
 
 		// --- Execute the handler (measure only handler duration) ---
-		start := time.Now()
-		handlerErr := c.Next()
-		duration := float64(time.Since(start).Milliseconds())
+		in.StartedAt = time.Now()
+		var handlerErr error
+		if cfg.CaptureStackTraces {
+			handlerErr, in.Stack = nextRecovering(c)
+		} else {
+			handlerErr = c.Next()
+		}
+		in.Duration = time.Since(in.StartedAt)
+		in.Timings = captureTimings(c)
 
 		// If the handler returned an error (e.g. fiber.NewError(400, "msg")
 		// or a raw GORM error), Fiber's ErrorHandler has NOT run yet — the
@@ -82,78 +245,65 @@ func New(cfg MiddlewareConfig) fiber.Handler {
 		// --- Capture response data (synchronous – after handler) ---
 		// At this point the response is fully populated: either the handler
 		// wrote it directly, or the ErrorHandler just wrote it above.
-		statusCode := c.Response().StatusCode()
+		in.StatusCode = c.Response().StatusCode()
 
 		// ignore 404 status code
-		if statusCode == 404 && !strings.HasPrefix(path, "/api/") {
+		if in.StatusCode == 404 && !strings.HasPrefix(path, "/api/") {
 			return nil
 		}
 
-		success := statusCode < 400
+		success := in.StatusCode < 400
+		if cfg.SuccessFunc != nil {
+			success = cfg.SuccessFunc(in.StatusCode, c)
+			in.Success = &success
+		}
 
-		var respBody json.RawMessage
-		if cfg.CaptureRespBody {
-			respBody = copyBytes(c.Response().Body(), cfg.MaxBodySize)
+		slow := cfg.CaptureBodyOverDurationMs > 0 &&
+			float64(in.Duration.Microseconds())/1000 > cfg.CaptureBodyOverDurationMs
+		if cfg.CaptureReqBody && deferReqBody && ((cfg.CaptureReqBodyOnErrorOnly && !success) || slow) {
+			in.RequestBody = cfg.captureBody(c.Get(fiber.HeaderContentType), c.Body(), cfg.MaxReqBodySize)
+		}
+		if cfg.CaptureRespBody && (!deferRespBody || (cfg.CaptureRespBodyOnErrorOnly && !success) || slow) {
+			in.ResponseBody = cfg.captureBody(string(c.Response().Header.ContentType()), c.Response().Body(), cfg.MaxRespBodySize)
 		}
 
 		// Capture the raw Go error (e.g. GORM errors) for debugging.
 		// This preserves the full error chain separately from the
 		// client-facing response body written by the ErrorHandler.
-		var exception interface{}
 		if handlerErr != nil {
-			exception = handlerErr.Error()
+			in.Exception = handlerErr.Error()
 		}
 
-		respHeaders := captureResponseHeaders(c)
+		in.ResponseHeaders = captureResponseHeaders(c)
 
-		// Normalized route path (e.g. /api/users/:id).
-		routePath := c.Route().Path
+		// Normalized route path (e.g. /api/users/:id), falling back to
+		// path normalization when the route path is empty.
+		in.Path = c.Route().Path
+		if in.Path == "" {
+			in.Path = normalizePath(reqOriginalURL)
+		}
+		// Group by logical operation (e.g. GraphQL) when one was extracted.
+		if operation != "" {
+			in.Path = operation
+		}
 
 		// Full URL including protocol + host.
-		fullURL := buildFullURL(c)
+		in.URL = buildFullURL(c)
 
 		// Authenticated user (if any).
-		userJSON := captureUser(c, cfg.UserContextKey)
-
-		// Build JSON fields.
-		requestJSON, _ := json.Marshal(map[string]interface{}{
-			"ip":       reqIP,
-			"headers":  reqHeaders,
-			"params":   reqParams,
-			"queries":  reqQueries,
-			"body":     reqBody,
-			"datetime": start,
-		})
-
-		responseJSON, _ := json.Marshal(map[string]interface{}{
-			"statusCode": statusCode,
-			"body":       respBody,
-			"exception":  exception,
-			"datetime":   time.Now(),
-		})
-
-		respHeadersJSON, _ := json.Marshal(respHeaders)
-
-		// Fallback path normalization when route path is empty.
-		if routePath == "" {
-			routePath = normalizePath(reqOriginalURL)
-		}
-
-		entry := models.RequestLog{
-			Key:             "apis-traffic",
-			Path:            routePath,
-			URL:             fullURL,
-			Method:          reqMethod,
-			User:            datatypes.JSON(userJSON),
-			Request:         datatypes.JSON(requestJSON),
-			Response:        datatypes.JSON(responseJSON),
-			ResponseHeaders: datatypes.JSON(respHeadersJSON),
-			Success:         success,
-			Duration:        duration,
+		in.User = captureUser(c, cfg.UserContextKey)
+
+		if cfg.KeyFunc != nil {
+			in.Key = cfg.KeyFunc(c)
 		}
+		in.Tags = captureTags(c)
 
 		// Non-blocking enqueue — all DB work happens in the Writer goroutine.
-		cfg.Writer.Write(entry)
+		cfg.Recorder.Record(in)
+		if cfg.ExposeBufferHeader {
+			stats := cfg.Recorder.Stats()
+			c.Set("X-Monitoring-Buffer", strconv.Itoa(stats.Buffered)+"/"+strconv.Itoa(stats.Capacity))
+		}
 
 		// Return nil — we already invoked the ErrorHandler above,
 		// so Fiber must not call it a second time.
@@ -163,6 +313,98 @@ func New(cfg MiddlewareConfig) fiber.Handler {
 
 // --- helpers ---
 
+// maxOperationNameLen caps GraphQL operation names stored as the path.
+const maxOperationNameLen = 100
+
+// graphQLName matches a valid GraphQL name.
+var graphQLName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// GraphQLOperationName extracts the operationName field from a JSON
+// POST body. It returns "" for anything that is not a GraphQL request,
+// and skips JSON decoding unless the body mentions operationName.
+// Names that are not valid GraphQL names or are longer than 100
+// characters are ignored, since clients choose them freely.
+func GraphQLOperationName(c *fiber.Ctx) string {
+	if c.Method() != fiber.MethodPost {
+		return ""
+	}
+	body := c.Body()
+	if !bytes.Contains(body, []byte(`"operationName"`)) {
+		return ""
+	}
+	var payload struct {
+		OperationName string `json:"operationName"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	name := payload.OperationName
+	if len(name) > maxOperationNameLen || !graphQLName.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+// graphQLOperationAt returns an OperationNameExtractor that applies
+// GraphQLOperationName to requests for path only.
+func graphQLOperationAt(path string) func(*fiber.Ctx) string {
+	return func(c *fiber.Ctx) string {
+		if c.Path() != path {
+			return ""
+		}
+		return GraphQLOperationName(c)
+	}
+}
+
+// traceID extracts the correlation ID from the incoming request, or
+// generates a new one when none is present or it does not match
+// traceIDRe.
+func (cfg MiddlewareConfig) traceID(c *fiber.Ctx) string {
+	// Reuse the ID of an outer monitoring middleware so a doubly
+	// registered middleware logs both entries under one trace.
+	if v, ok := c.Locals(traceIDLocalsKey).(string); ok && v != "" {
+		return v
+	}
+	if cfg.TraceHeader != "" {
+		if v := c.Get(cfg.TraceHeader); traceIDRe.MatchString(v) {
+			return v
+		}
+		return uuid.NewString()
+	}
+	if v := c.Get(fiber.HeaderXRequestID); traceIDRe.MatchString(v) {
+		return v
+	}
+	// traceparent: version-traceid-parentid-flags
+	if parts := strings.Split(c.Get("traceparent"), "-"); len(parts) == 4 && traceIDRe.MatchString(parts[1]) {
+		return parts[1]
+	}
+	return uuid.NewString()
+}
+
+// headerEnabled reports whether the request header name is set to a
+// true value ("1", "true" or "yes"). An empty name is never enabled.
+func headerEnabled(c *fiber.Ctx, name string) bool {
+	if name == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Get(name))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// traceIDLocalsKey holds the trace ID of the request being captured.
+const traceIDLocalsKey = "monitoringTraceID"
+
+// traceResponseHeader is the header used to echo the correlation ID.
+func (cfg MiddlewareConfig) traceResponseHeader() string {
+	if cfg.TraceHeader != "" {
+		return cfg.TraceHeader
+	}
+	return fiber.HeaderXRequestID
+}
+
 func captureRequestHeaders(c *fiber.Ctx) map[string]string {
 	h := make(map[string]string)
 	c.Request().Header.VisitAll(func(key, value []byte) {
@@ -213,17 +455,109 @@ func normalizePath(raw string) string {
 	return strings.Join(segments, "/")
 }
 
-// copyBytes returns a safe copy of src, truncated to maxLen bytes.
-// If maxLen < 0 the full slice is copied.
+// captureBody copies body up to maxLen bytes, or returns the skipped
+// marker when contentType matches SkipBodyContentTypes.
+func (cfg MiddlewareConfig) captureBody(contentType string, body []byte, maxLen int) json.RawMessage {
+	if len(body) > 0 && matchContentType(contentType, cfg.SkipBodyContentTypes) {
+		return skippedBodyMarker
+	}
+	return copyBytes(body, maxLen)
+}
+
+// matchContentType reports whether contentType (parameters ignored)
+// matches any pattern, case-insensitively.
+func matchContentType(contentType string, patterns []string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	if ct == "" {
+		return false
+	}
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(ct, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+			continue
+		}
+		if p != "" && strings.HasPrefix(ct, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncatedBody replaces a body longer than its cap so the stored value
+// stays valid JSON and shows that it is incomplete.
+type truncatedBody struct {
+	Truncated    bool   `json:"_truncated"`
+	OriginalSize int    `json:"_originalSize"`
+	Body         string `json:"body,omitempty"`
+	Base64       string `json:"_base64,omitempty"`
+}
+
+// binaryBody holds a body that cannot be stored as a JSON string.
+type binaryBody struct {
+	Base64 string `json:"_base64"`
+}
+
+// copyBytes returns a safe copy of src that is always valid JSON. If src
+// is longer than maxLen bytes, its first maxLen bytes are wrapped as
+// {"_truncated":true,"_originalSize":N,"body":"..."}; other non-JSON
+// bodies (HTML error pages, form posts) are stored as a JSON string.
+// Bodies that are not valid UTF-8 or contain NUL bytes are stored
+// base64-encoded as {"_base64":"..."}, or under "_base64" instead of
+// "body" when truncated. If maxLen < 0 the full slice is copied.
 func copyBytes(src []byte, maxLen int) json.RawMessage {
 	if len(src) == 0 {
 		return nil
 	}
-	n := len(src)
-	if maxLen >= 0 && n > maxLen {
-		n = maxLen
+	if maxLen >= 0 && len(src) > maxLen {
+		body := truncatedBody{Truncated: true, OriginalSize: len(src)}
+		if prefix := trimPartialRune(src[:maxLen]); textSafe(prefix) {
+			body.Body = string(prefix)
+		} else {
+			body.Base64 = base64.StdEncoding.EncodeToString(src[:maxLen])
+		}
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil
+		}
+		return b
 	}
-	dst := make([]byte, n)
-	copy(dst, src[:n])
+	if !textSafe(src) || bytes.Contains(src, []byte(`\u0000`)) {
+		b, err := json.Marshal(binaryBody{Base64: base64.StdEncoding.EncodeToString(src)})
+		if err != nil {
+			return nil
+		}
+		return b
+	}
+	if !json.Valid(src) {
+		b, err := json.Marshal(string(src))
+		if err != nil {
+			return nil
+		}
+		return b
+	}
+	dst := make([]byte, len(src))
+	copy(dst, src)
 	return dst
 }
+
+// textSafe reports whether b survives as a JSON string: json.Marshal
+// replaces invalid UTF-8, and Postgres jsonb rejects \u0000.
+func textSafe(b []byte) bool {
+	return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+// trimPartialRune drops a multi-byte character cut off at the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
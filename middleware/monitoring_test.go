@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCopyBytes(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe}
+	tests := []struct {
+		name   string
+		src    []byte
+		maxLen int
+		want   string
+	}{
+		{"empty", nil, -1, ""},
+		{"json", []byte(`{"a":1}`), -1, `{"a":1}`},
+		{"html", []byte("<html><body>Bad Gateway</body></html>"), -1, `"\u003chtml\u003e\u003cbody\u003eBad Gateway\u003c/body\u003e\u003c/html\u003e"`},
+		{"form", []byte("a=1&b=é"), -1, `"a=1\u0026b=é"`},
+		{"binary", binary, -1, `{"_base64":"` + base64.StdEncoding.EncodeToString(binary) + `"}`},
+		{"nul in text", []byte("a\x00b"), -1, `{"_base64":"YQBi"}`},
+		{"escaped nul in json", []byte(`{"a":"\u0000"}`), -1, `{"_base64":"eyJhIjoiXHUwMDAwIn0="}`},
+		{"invalid utf8", []byte("caf\xe9"), -1, `{"_base64":"Y2Fm6Q=="}`},
+		{"truncated html", []byte("<p>hello</p>"), 3, `{"_truncated":true,"_originalSize":12,"body":"\u003cp\u003e"}`},
+		{"truncated mid-rune", []byte("aé"), 2, `{"_truncated":true,"_originalSize":3,"body":"a"}`},
+		{"truncated binary", binary, 5, `{"_truncated":true,"_originalSize":7,"_base64":"iVBORwA="}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := copyBytes(tt.src, tt.maxLen)
+			if string(got) != tt.want {
+				t.Errorf("copyBytes() = %s, want %s", got, tt.want)
+			}
+			if len(got) > 0 && !json.Valid(got) {
+				t.Errorf("copyBytes() = %s, not valid JSON", got)
+			}
+		})
+	}
+}
+
+func TestCopyBytesBase64RoundTrip(t *testing.T) {
+	src := make([]byte, 256)
+	for i := range src {
+		src[i] = byte(i)
+	}
+	var body binaryBody
+	if err := json.Unmarshal(copyBytes(src, -1), &body); err != nil {
+		t.Fatal(err)
+	}
+	got, err := base64.StdEncoding.DecodeString(body.Base64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(src) {
+		t.Errorf("round trip lost bytes")
+	}
+}
+
+func TestGraphQLOperationAt(t *testing.T) {
+	tests := []struct {
+		name, path, body, want string
+	}{
+		{"graphql path", "/graphql", `{"operationName":"GetUser","query":"{}"}`, "GetUser"},
+		{"other path", "/api/login", `{"operationName":"GetUser"}`, ""},
+		{"no operation", "/graphql", `{"query":"{}"}`, ""},
+		{"invalid name", "/graphql", `{"operationName":"../admin?x=1"}`, ""},
+		{"too long", "/graphql", `{"operationName":"` + strings.Repeat("a", 101) + `"}`, ""},
+	}
+	extract := graphQLOperationAt("/graphql")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			app := fiber.New()
+			app.Post("/*", func(c *fiber.Ctx) error {
+				got = extract(c)
+				return nil
+			})
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("operation = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		name, header, value, want string
+	}{
+		{"request id", "X-Request-Id", "req-1.a:b_c", "req-1.a:b_c"},
+		{"traceparent", "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"junk", "X-Request-Id", "<script>", ""},
+		{"too long", "X-Request-Id", strings.Repeat("a", 129), ""},
+		{"junk traceparent", "traceparent", "00-a b-c-01", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				got = MiddlewareConfig{}.traceID(c)
+				return nil
+			})
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(tt.header, tt.value)
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if !uuidRe.MatchString(got) {
+					t.Errorf("traceID() = %q, want a generated UUID", got)
+				}
+			} else if got != tt.want {
+				t.Errorf("traceID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimerConcurrentSpans(t *testing.T) {
+	var got map[string]time.Duration
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		timer := Timings(c)
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				span := timer.StartSpan("work")
+				time.Sleep(time.Millisecond)
+				span.End()
+			}()
+		}
+		wg.Wait()
+		got = captureTimings(c)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got["work"] < 8*time.Millisecond {
+		t.Errorf("work = %v, want the sum of 8 spans of at least 1ms", got["work"])
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// nextRecovering runs the rest of the chain like c.Next, but turns a
+// panic into an error (so the app's ErrorHandler answers with 500) and
+// returns the goroutine stack captured at the panic site.
+func nextRecovering(c *fiber.Ctx) (err error, stack string) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			stack = string(debug.Stack())
+		}
+	}()
+	return c.Next(), ""
+}
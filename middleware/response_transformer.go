@@ -2,12 +2,44 @@ package middleware
 
 import (
 	"encoding/json"
+	"path"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/aghiadodeh/go-monitoring/dto"
 )
 
+// NewResponseTransformer returns a ResponseTransformer that passes
+// responses for the given paths through untouched. Each entry is either a
+// path prefix (e.g. "/api/monitoring/requests/export") or, when it
+// contains a wildcard, a path.Match pattern (e.g. "/api/monitoring/*/stream").
+func NewResponseTransformer(skipPaths []string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if matchesAny(ctx.Path(), skipPaths) {
+			return ctx.Next()
+		}
+		return ResponseTransformer(ctx)
+	}
+}
+
+func matchesAny(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(p, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseTransformer wraps successful responses in a dto.BaseResponse
+// and converts *fiber.Error values into error responses.
 func ResponseTransformer(ctx *fiber.Ctx) error {
 	// Call next middleware/handler
 	err := ctx.Next()
@@ -39,26 +71,20 @@ func ResponseTransformer(ctx *fiber.Ctx) error {
 		return nil
 	}
 
-	// Get the original response body
+	// Get the original response body. Valid JSON of any shape (object,
+	// array, string, number, bool, null) is decoded once so it is embedded
+	// as-is; anything else is treated as a raw string.
 	originalBody := ctx.Response().Body()
 	var data any
 	if len(originalBody) > 0 {
 		if err := json.Unmarshal(originalBody, &data); err != nil {
-			// fallback: treat body as raw string or binary if it's not valid JSON
 			data = string(originalBody)
 		}
 	}
 
-	// Check if response is already a BaseResponse
-	var maybeMap map[string]interface{}
-	if err := json.Unmarshal(originalBody, &maybeMap); err == nil {
-		_, hasSuccess := maybeMap["success"]
-		_, hasData := maybeMap["data"]
-		_, hasMessage := maybeMap["message"]
-		if hasSuccess && hasData && hasMessage {
-			// Already in base response format
-			return nil
-		}
+	// Only objects can already be in base response format.
+	if isBaseResponse(data) {
+		return nil
 	}
 
 	// If we got here, the response wasn't a BaseResponse, so we'll transform it
@@ -71,3 +97,16 @@ func ResponseTransformer(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(response)
 }
+
+// isBaseResponse reports whether data is a decoded JSON object that
+// already carries the success/data/message envelope.
+func isBaseResponse(data any) bool {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, hasSuccess := m["success"]
+	_, hasData := m["data"]
+	_, hasMessage := m["message"]
+	return hasSuccess && hasData && hasMessage
+}
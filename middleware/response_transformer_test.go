@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// transform runs body through ResponseTransformer and returns the
+// response body.
+func transform(t *testing.T, status int, body string, skipPaths ...string) string {
+	t.Helper()
+	app := fiber.New()
+	app.Use(NewResponseTransformer(skipPaths))
+	app.Get("/*", func(c *fiber.Ctx) error {
+		return c.Status(status).SendString(body)
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/monitoring/x", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestResponseTransformerWrapsBodies(t *testing.T) {
+	tests := []struct {
+		name, body, data string
+	}{
+		{"object", `{"id":1}`, `{"id":1}`},
+		{"array", `[1,2,3]`, `[1,2,3]`},
+		{"empty array", `[]`, `[]`},
+		{"number", `42`, `42`},
+		{"json string", `"hi"`, `"hi"`},
+		{"bool", `true`, `true`},
+		{"null", `null`, `null`},
+		{"text", `plain text`, `"plain text"`},
+		{"empty", ``, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Success    bool            `json:"success"`
+				Data       json.RawMessage `json:"data"`
+				Message    string          `json:"message"`
+				StatusCode int             `json:"statusCode"`
+			}
+			out := transform(t, fiber.StatusOK, tt.body)
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("response %q is not JSON: %v", out, err)
+			}
+			if !got.Success || got.StatusCode != fiber.StatusOK || got.Message == "" {
+				t.Errorf("envelope = %s", out)
+			}
+			if string(got.Data) != tt.data {
+				t.Errorf("data = %s, want %s", got.Data, tt.data)
+			}
+		})
+	}
+}
+
+func TestResponseTransformerPassesThrough(t *testing.T) {
+	base := `{"success":true,"data":[],"message":"ok","statusCode":200}`
+	if got := transform(t, fiber.StatusOK, base); got != base {
+		t.Errorf("base response rewrapped: %s", got)
+	}
+	if got := transform(t, fiber.StatusBadRequest, `{"message":"bad"}`); got != `{"message":"bad"}` {
+		t.Errorf("error response rewrapped: %s", got)
+	}
+	if got := transform(t, fiber.StatusOK, `a,b`, "/api/monitoring/*"); got != `a,b` {
+		t.Errorf("skipped path rewrapped: %s", got)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TagsLocalsKey is the c.Locals key handlers use to tag a request, e.g.
+//
+//	c.Locals(middleware.TagsLocalsKey, []string{"checkout-flow"})
+//	c.Locals(middleware.TagsLocalsKey, map[string]string{"experiment": "B"})
+//
+// The middleware reads it after the handler returns.
+const TagsLocalsKey = "monitoring_tags"
+
+// captureTags normalizes the handler's tags into a JSON object: plain
+// tags become keys with the value true, so every tag can be filtered by
+// key. It returns nil when no tags were set.
+func captureTags(c *fiber.Ctx) map[string]any {
+	var tags map[string]any
+	set := func(k string, v any) {
+		if k == "" {
+			return
+		}
+		if tags == nil {
+			tags = make(map[string]any)
+		}
+		tags[k] = v
+	}
+
+	switch v := c.Locals(TagsLocalsKey).(type) {
+	case string:
+		set(v, true)
+	case []string:
+		for _, t := range v {
+			set(t, true)
+		}
+	case []any:
+		for _, t := range v {
+			set(fmt.Sprint(t), true)
+		}
+	case map[string]string:
+		for k, t := range v {
+			set(k, t)
+		}
+	case map[string]any:
+		for k, t := range v {
+			set(k, t)
+		}
+	}
+	return tags
+}
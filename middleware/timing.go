@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// timingsLocalsKey is the c.Locals key holding the request's timings.
+const timingsLocalsKey = "monitoringTimings"
+
+// timings accumulates named durations for a single request. Spans may
+// end on other goroutines than the handler's, so access is synchronized.
+type timings struct {
+	mu sync.Mutex
+	d  map[string]time.Duration
+}
+
+func (t *timings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	t.d[name] += d
+	t.mu.Unlock()
+}
+
+func (t *timings) snapshot() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.d) == 0 {
+		return nil
+	}
+	out := make(map[string]time.Duration, len(t.d))
+	for k, v := range t.d {
+		out[k] = v
+	}
+	return out
+}
+
+// Span measures one named section of a handler (e.g. "db", "cache").
+// Spans with the same name are summed.
+type Span struct {
+	t     *timings
+	name  string
+	start time.Time
+	once  sync.Once
+}
+
+// StartSpan starts timing a named section of the current request. Call
+// End when the section finishes; the accumulated durations are stored
+// with the request log as a server-timing style breakdown.
+//
+// Like every fiber.Ctx method, StartSpan must be called on the handler's
+// goroutine. The returned Span may be ended from any goroutine; to start
+// spans from goroutines the handler spawns, pass them a Timer instead.
+func StartSpan(c *fiber.Ctx, name string) *Span {
+	return Timings(c).StartSpan(name)
+}
+
+// Timer starts spans for one request. Unlike fiber.Ctx it is safe for
+// concurrent use: get it with Timings before spawning goroutines and
+// hand it to them.
+type Timer struct {
+	t *timings
+}
+
+// Timings returns the Timer of the current request. Call it on the
+// handler's goroutine.
+func Timings(c *fiber.Ctx) *Timer {
+	t, ok := c.Locals(timingsLocalsKey).(*timings)
+	if !ok {
+		t = &timings{d: make(map[string]time.Duration)}
+		c.Locals(timingsLocalsKey, t)
+	}
+	return &Timer{t: t}
+}
+
+// StartSpan starts timing a named section, as the StartSpan function.
+func (tm *Timer) StartSpan(name string) *Span {
+	return &Span{t: tm.t, name: name, start: time.Now()}
+}
+
+// End stops the span and records its duration. Extra calls are no-ops.
+func (s *Span) End() {
+	s.once.Do(func() {
+		s.t.add(s.name, time.Since(s.start))
+	})
+}
+
+// captureTimings returns the durations recorded via StartSpan, or nil.
+func captureTimings(c *fiber.Ctx) map[string]time.Duration {
+	t, ok := c.Locals(timingsLocalsKey).(*timings)
+	if !ok {
+		return nil
+	}
+	return t.snapshot()
+}
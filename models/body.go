@@ -0,0 +1,97 @@
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	"gorm.io/datatypes"
+)
+
+// gzipEnvelopeKey marks a body that was stored gzip-compressed and
+// base64-encoded: {"_gz": "<base64>"}.
+const gzipEnvelopeKey = "_gz"
+
+// CompressBody gzip-compresses body and wraps it in a {"_gz": "..."}
+// envelope so it can be stored inside a JSON column. If compression
+// fails, body is returned unchanged.
+func CompressBody(body []byte) json.RawMessage {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return body
+	}
+	if err := zw.Close(); err != nil {
+		return body
+	}
+	env, err := json.Marshal(map[string]string{
+		gzipEnvelopeKey: base64.StdEncoding.EncodeToString(buf.Bytes()),
+	})
+	if err != nil {
+		return body
+	}
+	return env
+}
+
+// decompressBody reverses CompressBody. ok is false when raw is not a
+// gzip envelope.
+func decompressBody(raw json.RawMessage) (body json.RawMessage, ok bool) {
+	var env map[string]string
+	if err := json.Unmarshal(raw, &env); err != nil || len(env) != 1 {
+		return nil, false
+	}
+	encoded, found := env[gzipEnvelopeKey]
+	if !found {
+		return nil, false
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, false
+	}
+	return plain, true
+}
+
+// DecodeBodies transparently decompresses request and response bodies
+// that were stored with CompressBody. Records without compressed bodies
+// are left untouched.
+func (r *RequestLog) DecodeBodies() {
+	r.Request = decodeBodyField(r.Request)
+	r.Response = decodeBodyField(r.Response)
+}
+
+func decodeBodyField(col datatypes.JSON) datatypes.JSON {
+	if len(col) == 0 {
+		return col
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(col, &fields); err != nil {
+		return col
+	}
+	body, ok := decompressBody(fields["body"])
+	if !ok {
+		return col
+	}
+	if json.Valid(body) {
+		fields["body"] = body
+	} else {
+		// Non-JSON bodies are returned as a JSON string.
+		s, _ := json.Marshal(string(body))
+		fields["body"] = s
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return col
+	}
+	return datatypes.JSON(out)
+}
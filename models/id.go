@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// idGenerator produces primary keys for new log rows.
+var idGenerator = uuid.NewString
+
+// SetIDGenerator replaces the function used to generate log IDs (default:
+// UUIDv4). Generated values must parse as UUIDs, since the id columns are
+// UUID typed; invalid values fall back to UUIDv4.
+//
+// Time-ordered IDs such as UUIDv7, or ULIDs in UUID form, keep new rows
+// at the end of the primary-key index. On high-insert tables this avoids
+// the random page splits and cache misses caused by UUIDv4:
+//
+//	models.SetIDGenerator(func() string { return uuid.Must(uuid.NewV7()).String() })
+//	models.SetIDGenerator(func() string { return uuid.UUID(ulid.Make()).String() })
+//
+// Passing nil restores the default.
+func SetIDGenerator(fn func() string) {
+	if fn == nil {
+		fn = uuid.NewString
+	}
+	idGenerator = fn
+}
+
+// newID returns a generated ID, falling back to UUIDv4 when the
+// configured generator returns something that is not a UUID.
+func newID() uuid.UUID {
+	id, err := uuid.Parse(idGenerator())
+	if err != nil {
+		return uuid.New()
+	}
+	return id
+}
+
+// BeforeCreate assigns an ID from the configured generator and, with
+// SetUTCTimestamps, UTC creation times.
+func (r *RequestLog) BeforeCreate(*gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = newID()
+	}
+	if utcTimestamps && r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now().UTC()
+		r.UpdatedAt = r.CreatedAt
+	}
+	return nil
+}
+
+// BeforeCreate assigns an ID from the configured generator and, with
+// SetUTCTimestamps, UTC creation times.
+func (j *JobLog) BeforeCreate(*gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = newID()
+	}
+	if utcTimestamps && j.CreatedAt.IsZero() {
+		j.CreatedAt = time.Now().UTC()
+		j.UpdatedAt = j.CreatedAt
+	}
+	return nil
+}
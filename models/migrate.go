@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MigrateJSONB converts the JSON columns of the monitoring tables from
+// json to jsonb on PostgreSQL, which makes the ->> lookups used by the
+// analytics queries much faster. Columns that are already jsonb are left
+// alone; on other dialects it does nothing. The conversion rewrites the
+// table, so run it during a maintenance window on large tables.
+func MigrateJSONB(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, model := range []any{&RequestLog{}, &JobLog{}} {
+		if err := migrateJSONB(db, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateJSONB(db *gorm.DB, model any) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	columns, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	for _, col := range columns {
+		if !strings.EqualFold(col.DatabaseTypeName(), "json") {
+			continue
+		}
+		err := db.Exec("ALTER TABLE ? ALTER COLUMN ? TYPE jsonb USING ?::jsonb",
+			clause.Table{Name: stmt.Table}, clause.Column{Name: col.Name()}, clause.Column{Name: col.Name()}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
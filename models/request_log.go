@@ -8,23 +8,43 @@ import (
 )
 
 // RequestLog stores a single HTTP request/response cycle.
+// JSON columns have no explicit type, so migrations use the dialect's
+// native one (JSONB on PostgreSQL, JSON on MySQL and SQLite).
 type RequestLog struct {
 	ID              uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	Key             string         `gorm:"type:varchar(255)" json:"key"`
 	Path            string         `gorm:"type:varchar(500)" json:"path"`
 	URL             string         `gorm:"type:varchar(2048)" json:"url"`
 	Method          string         `gorm:"type:varchar(10)" json:"method"`
-	User            datatypes.JSON `gorm:"type:json" json:"user"`
-	Request         datatypes.JSON `gorm:"type:json" json:"request"`
-	Response        datatypes.JSON `gorm:"type:json" json:"response"`
-	ResponseHeaders datatypes.JSON `gorm:"type:json" json:"responseHeaders"`
+	User            datatypes.JSON `json:"user"`
+	Request         datatypes.JSON `json:"request"`
+	Response        datatypes.JSON `json:"response"`
+	ResponseHeaders datatypes.JSON `json:"responseHeaders"`
+	RouteParams     datatypes.JSON `json:"routeParams"`
+	Timings         datatypes.JSON `json:"timings"`
+	Tags            datatypes.JSON `json:"tags"`
+	IndexedFields   datatypes.JSON `json:"indexedFields"`
 	Success         bool           `gorm:"not null" json:"success"`
 	Duration        float64        `gorm:"type:double precision" json:"duration"`
+	TraceID         string         `gorm:"type:varchar(255);index" json:"traceId"`
 	CreatedAt       time.Time      `gorm:"index" json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
+
+	// IP is the raw client IP, carried to the Writer for enrichment.
+	// It is not persisted; the stored IP lives in Request.
+	IP string `gorm:"-" json:"-"`
+
+	// EnqueuedAt is set by the Writer when the entry is buffered, to
+	// measure writer lag. It is not persisted.
+	EnqueuedAt time.Time `gorm:"-" json:"-"`
+
+	// StartedAt is when the request began. The Writer's dedup uses it to
+	// tell a request logged twice from other requests of the same trace.
+	// It is not persisted; CreatedAt is set on insert.
+	StartedAt time.Time `gorm:"-" json:"-"`
 }
 
-// TableName overrides the default table name.
+// TableName overrides the default table name, honouring SetTablePrefix.
 func (RequestLog) TableName() string {
-	return "monitoring_request_logs"
+	return tablePrefix + "monitoring_request_logs"
 }
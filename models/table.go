@@ -0,0 +1,29 @@
+package models
+
+// tablePrefix is prepended to every monitoring table name.
+var tablePrefix string
+
+// SetTablePrefix sets a prefix (e.g. "app_" or a schema such as "audit.")
+// applied to all monitoring table names. GORM caches table names per
+// model, so it must be called before the models are first used — Setup
+// does this from Config.TablePrefix.
+func SetTablePrefix(prefix string) {
+	tablePrefix = prefix
+}
+
+// TablePrefix returns the current table name prefix.
+func TablePrefix() string {
+	return tablePrefix
+}
+
+// utcTimestamps makes new rows carry UTC created_at/updated_at values.
+var utcTimestamps bool
+
+// SetUTCTimestamps stores created_at/updated_at of new logs in UTC
+// instead of the server's local zone. Enable it when the columns have no
+// time zone (e.g. TIMESTAMP WITHOUT TIME ZONE or MySQL DATETIME) so that
+// rows written from servers in different zones, or across DST changes,
+// compare consistently with the UTC date ranges used by the API.
+func SetUTCTimestamps(enabled bool) {
+	utcTimestamps = enabled
+}
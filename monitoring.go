@@ -12,36 +12,58 @@
 //	m := monitoring.Setup(app, db, &cfg)    // custom config
 //
 //	m.LogJob("send-emails", true, map[string]any{"count": 42})
+//	monitoring.LogJob("send-emails", true, nil) // uses the Monitor from Setup
 package monitoring
 
 import (
+	"context"
 	"io/fs"
-	"mime"
+	"log"
 	"os"
-	"path"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/aghiadodeh/go-monitoring/auth"
+	"github.com/aghiadodeh/go-monitoring/core"
+	"github.com/aghiadodeh/go-monitoring/dto"
 	"github.com/aghiadodeh/go-monitoring/handlers"
 	"github.com/aghiadodeh/go-monitoring/logwriter"
 	"github.com/aghiadodeh/go-monitoring/middleware"
+	"github.com/aghiadodeh/go-monitoring/models"
+	"github.com/aghiadodeh/go-monitoring/otel"
 	"github.com/aghiadodeh/go-monitoring/services"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
 	"gorm.io/gorm"
 )
 
 // Monitor is the main handle returned by Setup.
 // Use it to log jobs and to shut down gracefully.
 type Monitor struct {
+	db         *gorm.DB
 	config     *Config
 	writer     *logwriter.Writer
+	spans      *otel.Exporter
+	recorder   *core.Recorder
+	reqService *services.RequestService
 	jobService *services.JobService
+
+	signalled atomic.Bool   // set once HandleSignals starts a shutdown
+	stopped   chan struct{} // closed when that shutdown has flushed the logs
 }
 
 // Setup initializes the monitoring system:
 //   - registers the request-capture middleware (async, non-blocking)
-//   - registers the analytics API routes under /api/monitoring
-//   - optionally serves the frontend dashboard
+//   - registers the analytics API routes under APIBasePath (default /api/monitoring)
+//   - optionally serves the frontend dashboard under DashboardRoute (default /monitoring)
+//   - makes the Monitor the default for the package-level helpers
+//
+// db is the monitoring storage connection: the writer, the analytics
+// services and the health check all use it, and nothing else. Pass a
+// dedicated *gorm.DB (even to another database) to keep monitoring load
+// off your application's connection pool.
 //
 // Pass nil for cfg to use DefaultConfig() (reads from env vars).
 func Setup(app *fiber.App, db *gorm.DB, cfg ...*Config) *Monitor {
@@ -52,19 +74,72 @@ func Setup(app *fiber.App, db *gorm.DB, cfg ...*Config) *Monitor {
 		c = DefaultConfig()
 	}
 
+	apiBase := routePrefix(c.APIBasePath, DefaultAPIBasePath)
+	dashboardRoute := routePrefix(c.DashboardRoute, DefaultDashboardRoute)
+	skipPaths, transformerSkipPaths := c.mountPaths(apiBase, dashboardRoute)
+
+	// Must run before any query so GORM caches the prefixed table names.
+	models.SetTablePrefix(c.TablePrefix)
+	models.SetUTCTimestamps(c.UTCTimestamps)
+
 	// ---- async log writer ----
 	w := logwriter.New(db, logwriter.Options{
 		BufferSize:    c.BufferSize,
 		BatchSize:     c.BatchSize,
 		FlushInterval: c.FlushInterval,
 		Workers:       c.Workers,
+		MaxBatchBytes: c.MaxBatchBytes,
+		MaxDBConns:    c.MaxDBConns,
+		Dedup:         c.DedupWrites,
+		RecentWindow:  c.RecentWindow,
+
+		OnConflictDoNothing: c.OnConflictDoNothing,
+		ChunkSize:           c.InsertChunkSize,
+
+		OverflowPolicy: c.OverflowPolicy,
+		BlockTimeout:   c.BlockTimeout,
+
+		FallbackCapacity: c.FallbackCapacity,
+	})
+
+	// ---- optional OTLP span exporter (nil when no endpoint is set) ----
+	spans := otel.New(otel.Options{
+		Endpoint:      c.OTLPEndpoint,
+		ServiceName:   c.OTLPServiceName,
+		BufferSize:    c.BufferSize,
+		BatchSize:     c.BatchSize,
+		FlushInterval: c.FlushInterval,
 	})
 
+	// ---- framework-independent capture pipeline ----
+	recorder := &core.Recorder{
+		Writer:            w,
+		Spans:             spans,
+		CompressBodies:    c.CompressBodies,
+		CompressThreshold: c.CompressThreshold,
+	}
+
+	// ---- optional CORS for a dashboard on another origin ----
+	if len(c.DashboardCORS.AllowOrigins) > 0 {
+		credentials := c.DashboardCORS.AllowCredentials
+		if credentials && slices.Contains(c.DashboardCORS.AllowOrigins, "*") {
+			// cors.New panics on this combination, and browsers refuse it.
+			log.Println(`[go-monitoring] error: DashboardCORS cannot allow credentials for origin "*"; list the dashboard origins instead. Credentials are disabled.`)
+			credentials = false
+		}
+		app.Use(apiBase, cors.New(cors.Config{
+			AllowOrigins:     strings.Join(c.DashboardCORS.AllowOrigins, ","),
+			AllowCredentials: credentials,
+			AllowHeaders:     "Authorization, Content-Type",
+		}))
+	}
+
 	// ---- add response transformer middleware ----
+	transformer := middleware.NewResponseTransformer(transformerSkipPaths)
 	app.Use(func(c *fiber.Ctx) error {
 		path := c.Path()
-		if strings.HasPrefix(path, "/api/monitoring") {
-			return middleware.ResponseTransformer(c)
+		if strings.HasPrefix(path, apiBase) {
+			return transformer(c)
 		}
 		return c.Next()
 	})
@@ -72,43 +147,148 @@ func Setup(app *fiber.App, db *gorm.DB, cfg ...*Config) *Monitor {
 	// ---- request monitoring middleware (applied globally) ----
 	if c.RequestSaveEnabled {
 		app.Use(middleware.New(middleware.MiddlewareConfig{
+			Recorder:        recorder,
 			Writer:          w,
-			SkipPaths:       c.SkipPaths,
+			SkipPaths:       skipPaths,
 			UserContextKey:  c.UserContextKey,
 			MaxBodySize:     c.MaxBodySize,
+			MaxReqBodySize:  c.MaxReqBodySize,
+			MaxRespBodySize: c.MaxRespBodySize,
 			CaptureReqBody:  c.CaptureReqBody,
 			CaptureRespBody: c.CaptureRespBody,
+			TraceHeader:     c.TraceHeader,
+
+			SkipBodyContentTypes:   c.SkipBodyContentTypes,
+			OperationNameExtractor: c.OperationNameExtractor,
+			GraphQLPath:            c.GraphQLPath,
+			KeyFunc:                c.KeyFunc,
+			IPEnricher:             c.IPEnricher,
+			SuccessFunc:            c.SuccessFunc,
+			ExposeBufferHeader:     c.ExposeBufferHeader,
+
+			CaptureReqBodyOnErrorOnly:  c.CaptureReqBodyOnErrorOnly,
+			CaptureRespBodyOnErrorOnly: c.CaptureRespBodyOnErrorOnly,
+			CaptureBodyOverDurationMs:  c.CaptureBodyOverDurationMs,
+			IndexReqBodyFields:         c.IndexReqBodyFields,
+			SkipHeader:                 c.SkipHeader,
+			ForceHeader:                c.ForceHeader,
+			RestrictMethods:            c.RestrictMethods,
+			CaptureStackTraces:         c.CaptureStackTraces,
 		}))
 	}
 
 	// ---- services ----
-	reqService := &services.RequestService{DB: db}
-	jobService := &services.JobService{DB: db}
+	lookback := c.DefaultLookback
+	if lookback <= 0 {
+		lookback = 24 * time.Hour
+	}
+	reqService := &services.RequestService{
+		DB:                   db,
+		MethodGroups:         c.MethodGroups,
+		ExceptionStatusCodes: c.ExceptionStatusCodes,
+		MaxConcurrentAnalyze: c.MaxConcurrentAnalyze,
+		AnalyzeCacheTTL:      c.AnalyzeCacheTTL,
+		DefaultLookback:      lookback,
+		MaxAnalyzeRange:      c.MaxAnalyzeRange,
+		RelatedStrategy:      c.RelatedStrategy,
+		RelatedWindow:        c.RelatedWindow,
+		RelatedUserField:     c.RelatedUserField,
+		ExcludeFromAnalytics: c.ExcludeFromAnalytics,
+	}
+	jobService := &services.JobService{
+		DB:              db,
+		DefaultLookback: lookback,
+		OnClear:         reqService.InvalidateAnalyzeCache,
+	}
 
 	// ---- handlers ----
-	reqHandler := &handlers.RequestHandler{Service: reqService}
-	jobHandler := &handlers.JobHandler{Service: jobService}
+	reqHandler := &handlers.RequestHandler{Service: reqService, StrictPagination: c.StrictPagination}
+	jobHandler := &handlers.JobHandler{Service: jobService, StrictPagination: c.StrictPagination}
+	healthHandler := &handlers.HealthHandler{DB: db, Writer: w}
+	configHandler := &handlers.ConfigHandler{Config: dto.DashboardConfig{
+		AuthRequired:       c.AuthRequired || c.AuthValidator != nil || c.JWKSURL != "",
+		LoginEnabled:       c.AuthValidator == nil && c.JWKSURL == "",
+		APIsEnabled:        c.APIsEnabled,
+		HealthGuarded:      c.HealthGuarded,
+		RequestSaveEnabled: c.RequestSaveEnabled,
+		CaptureReqBody:     c.CaptureReqBody,
+		CaptureRespBody:    c.CaptureRespBody,
+
+		CaptureReqBodyOnErrorOnly:  c.CaptureReqBodyOnErrorOnly,
+		CaptureRespBodyOnErrorOnly: c.CaptureRespBodyOnErrorOnly,
+		CaptureBodyOverDurationMs:  c.CaptureBodyOverDurationMs,
+
+		DurationBoundaries: services.DurationBoundaries,
+		DefaultLookback:    lookback.Milliseconds(),
+		DashboardRoute:     dashboardRoute,
+		APIBasePath:        apiBase,
+	}}
 
 	// ---- routes ----
-	api := app.Group("/api/monitoring")
+	api := app.Group(apiBase)
 
-	// Public: authentication
-	api.Post("/authentication/login", auth.LoginHandler(c.Username, c.Password, c.JWTSecret))
+	// Public: authentication. Rate-limited attempts are not audited, so a
+	// brute-force run cannot flood the job table.
+	api.Post("/authentication/login",
+		auth.RateLimit(c.LoginRateLimit, c.LoginRateWindow, c.LoginRateStore),
+		handlers.AuditLogin(jobService),
+		auth.LoginHandler(c.Username, c.Password, c.JWTSecret,
+			auth.Credential{Username: c.ViewerUsername, Password: c.ViewerPassword, Role: auth.RoleViewer}),
+	)
+
+	// Public: effective (non-secret) config for the dashboard
+	api.Get("/config", configHandler.Get)
 
 	// Protected: analytics
-	protected := api.Group("", auth.Guard(c.AuthRequired, c.APIsEnabled, c.JWTSecret))
+	roles := auth.RoleMapping{Claim: c.RoleClaim, Map: c.RoleMap, Default: c.DefaultRole}
+	guard := auth.Guard(c.AuthRequired, c.APIsEnabled, c.JWTSecret, roles)
+	switch {
+	case c.AuthValidator != nil:
+		guard = auth.GuardWith(true, c.APIsEnabled, c.AuthValidator, roles)
+	case c.JWKSURL != "":
+		guard = auth.GuardWith(true, c.APIsEnabled, auth.JWKSValidator(c.JWKSURL, c.JWKSIssuer, c.JWKSAudience), roles)
+	}
+
+	// Health (public unless HealthGuarded is set). Registered before the
+	// protected group: Group("") mounts its middleware on the whole
+	// prefix, so later routes would always pass the guard and the
+	// analytics rate limiter.
+	if c.HealthGuarded {
+		api.Get("/health", guard, healthHandler.Health)
+		api.Get("/healthz", guard, healthHandler.Healthz)
+	} else {
+		api.Get("/health", healthHandler.Health)
+		api.Get("/healthz", healthHandler.Healthz)
+	}
+
+	protected := api.Group("", guard,
+		auth.APIRateLimit(c.AnalyticsRateLimit, c.AnalyticsRateWindow, c.AnalyticsRateStore))
+
+	// Distinct values for filter dropdowns
+	protected.Get("/facets", reqHandler.Facets)
+
+	// Live quick stats (in-memory, last RecentWindow)
+	protected.Get("/now", healthHandler.Now)
 
 	// Request logs
 	protected.Get("/requests", reqHandler.FindAll)
 	protected.Get("/requests/analyze", reqHandler.Analyze)
+	protected.Get("/requests/analyze/endpoints", reqHandler.AnalyzeByEndpoint)
+	protected.Get("/requests/analyze/compare", reqHandler.Compare)
+	protected.Get("/requests/analyze/slo", reqHandler.SLOReport)
+	protected.Get("/requests/analyze/bucket", reqHandler.BucketItems)
+	protected.Get("/requests/slowest", reqHandler.SlowestRequests)
+	protected.Get("/requests/export/ndjson", reqHandler.ExportNDJSON)
 	protected.Get("/requests/view/:id", reqHandler.FindByID)
+	protected.Get("/requests/view/:id/related", reqHandler.Related)
 
 	// Job logs
 	protected.Get("/jobs", jobHandler.FindAll)
+	protected.Get("/jobs/analyze", jobHandler.Analyze)
 	protected.Get("/jobs/:id", jobHandler.FindByID)
 
-	// Clear all
-	protected.Delete("/clear", jobHandler.ClearAll)
+	// Clear all (admins only)
+	protected.Delete("/clear", auth.RequireRole(auth.RoleAdmin), jobHandler.ClearAll)
 
 	// ---- optional static dashboard (SPA) ----
 	if c.DashboardEnabled {
@@ -126,75 +306,134 @@ func Setup(app *fiber.App, db *gorm.DB, cfg ...*Config) *Monitor {
 			dashFS = sub
 		}
 
-		// serveIndex sends index.html with the correct Content-Type.
-		// This is used both for the base route and as a SPA fallback.
-		serveIndex := func(ctx *fiber.Ctx) error {
-			html, err := fs.ReadFile(dashFS, "index.html")
-			if err != nil {
-				return ctx.Status(fiber.StatusNotFound).SendString("Dashboard not found")
-			}
-			ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
-			return ctx.Send(html)
-		}
-
-		app.Get("/monitoring", serveIndex)
-
-		// Wildcard handler: serve static files if they exist,
-		// otherwise fall back to index.html for SPA client-side routing.
-		app.Get("/monitoring/*", func(ctx *fiber.Ctx) error {
-			requestedPath := ctx.Params("*")
-			cleanPath := path.Clean(requestedPath)
-
-			// Prevent path traversal
-			if cleanPath == "." || strings.HasPrefix(cleanPath, "..") {
-				return serveIndex(ctx)
-			}
-
-			// Try to serve the static file
-			content, err := fs.ReadFile(dashFS, cleanPath)
-			if err != nil {
-				// File not found → SPA fallback
-				return serveIndex(ctx)
-			}
-
-			// Set Content-Type based on file extension
-			if ct := mime.TypeByExtension(path.Ext(cleanPath)); ct != "" {
-				ctx.Set(fiber.HeaderContentType, ct)
-			}
-			return ctx.Send(content)
-		})
+		dash := newDashboard(dashFS, c.DashboardPath == "", dashboardRoute)
+		app.Get(dashboardRoute, dash.serveIndex)
+		// Lets the SPA discover the API base relative to its own URL.
+		app.Get(dashboardRoute+"/config.json", configHandler.Get)
+		app.Get(dashboardRoute+"/*", dash.serveFile)
 	}
 
 	m := &Monitor{
+		db:         db,
 		config:     c,
 		writer:     w,
+		spans:      spans,
+		recorder:   recorder,
+		reqService: reqService,
 		jobService: jobService,
+		stopped:    make(chan struct{}),
 	}
 
 	// ---- auto-flush on server shutdown ----
 	// Fiber calls OnShutdown hooks when app.Shutdown() is invoked,
 	// which happens after the server stops accepting new requests.
 	// This ensures all in-flight entries are flushed before exit.
+	shutdownTimeout := c.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 10 * time.Second
+	}
 	app.Hooks().OnShutdown(func() error {
-		m.Shutdown()
-		return nil
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return m.Shutdown(ctx)
 	})
 
+	// ---- optional graceful shutdown on SIGINT/SIGTERM ----
+	if c.HandleSignals {
+		shutdownOnSignal(app, m, shutdownTimeout)
+	}
+
+	SetDefault(m)
 	return m
 }
 
+// MigrateJSONB converts existing json columns of the monitoring tables to
+// jsonb on PostgreSQL (a no-op elsewhere). Tables created from the models
+// already use jsonb there.
+func MigrateJSONB(db *gorm.DB) error {
+	return models.MigrateJSONB(db)
+}
+
+// DB returns the monitoring storage connection passed to Setup, e.g. to
+// create the tables with AutoMigrate on a dedicated database.
+func (m *Monitor) DB() *gorm.DB {
+	return m.db
+}
+
+// MigrateJSONB runs MigrateJSONB on the monitoring connection.
+func (m *Monitor) MigrateJSONB() error {
+	return models.MigrateJSONB(m.db)
+}
+
 // LogJob records a background / cron job execution.
 func (m *Monitor) LogJob(name string, success bool, metadata interface{}) error {
-	return m.jobService.Create(name, success, metadata)
+	return m.jobService.Create(context.Background(), name, success, metadata)
+}
+
+// JobLogInput is one job result for LogJobs.
+type JobLogInput = services.JobLogInput
+
+// LogJobs records many job executions with a single multi-row insert,
+// e.g. the results of a fan-out run. All metadata is validated first;
+// if any entry is invalid, nothing is stored.
+func (m *Monitor) LogJobs(entries []JobLogInput) error {
+	return m.jobService.CreateBatch(context.Background(), entries)
+}
+
+// RegisterJobSchema validates the metadata of every job named name
+// against a JSON Schema before it is logged; LogJob returns a descriptive
+// error on mismatch. Jobs without a registered schema are not validated.
+// See package schema for the supported keywords.
+func (m *Monitor) RegisterJobSchema(name string, schema []byte) error {
+	return m.jobService.RegisterSchema(name, schema)
 }
 
 // ClearAll deletes all monitoring data from the database.
 func (m *Monitor) ClearAll() error {
-	return m.jobService.ClearAll()
+	return m.jobService.ClearAll(context.Background())
+}
+
+// Clear deletes the monitoring data selected by opts.
+func (m *Monitor) Clear(opts services.ClearOptions) error {
+	return m.jobService.Clear(context.Background(), opts)
+}
+
+// ArchiveBefore moves all monitoring data created before t into dest and
+// removes it from the live tables. dest must have matching tables.
+func (m *Monitor) ArchiveBefore(t time.Time, dest *gorm.DB) error {
+	return m.jobService.ArchiveBefore(context.Background(), t, dest)
+}
+
+// Flush writes all buffered request logs to the database and waits until
+// they are stored or ctx expires, without stopping the writer. Use it in
+// integration tests or at checkpoints that need durability. It returns
+// an error when an insert failed or logs are left in the fallback buffer
+// (see logwriter.ErrFallbackPending).
+func (m *Monitor) Flush(ctx context.Context) error {
+	return m.writer.Flush(ctx)
 }
 
 // Shutdown flushes all pending log entries and stops background workers.
 // Call this when your application is shutting down.
-func (m *Monitor) Shutdown() {
-	m.writer.Shutdown()
+//
+// An optional context bounds how long Shutdown waits for the flush; when
+// it expires the remaining buffer is abandoned and ctx.Err() is returned.
+func (m *Monitor) Shutdown(ctx ...context.Context) error {
+	sctx := context.Background()
+	if len(ctx) > 0 && ctx[0] != nil {
+		sctx = ctx[0]
+	}
+	err := m.writer.ShutdownContext(sctx)
+	m.spans.Shutdown()
+	return err
+}
+
+// Wait blocks until a shutdown started by HandleSignals has drained the
+// app and flushed the logs. app.Listen returns as soon as the listeners
+// close, before that flush, so call Wait after it to keep main from
+// exiting early. It returns immediately when no signal was handled.
+func (m *Monitor) Wait() {
+	if m.signalled.Load() {
+		<-m.stopped
+	}
 }
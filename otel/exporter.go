@@ -0,0 +1,257 @@
+// Package otel exports captured requests as OpenTelemetry spans using the
+// OTLP/HTTP JSON protocol. It has no dependency on the OpenTelemetry SDK:
+// spans are queued on a buffered channel and posted in batches by a
+// background goroutine, so exporting never blocks request handling.
+package otel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is a single captured request to be exported.
+type Span struct {
+	TraceID    string // 32 hex chars, dashes ignored (e.g. a UUID); a random ID is generated when invalid
+	Name       string // normalized route path
+	Method     string
+	StatusCode int
+	Start      time.Time
+	End        time.Time
+}
+
+// Exporter posts spans to an OTLP/HTTP collector endpoint.
+type Exporter struct {
+	endpoint      string
+	serviceName   string
+	client        *http.Client
+	ch            chan Span
+	batchSize     int
+	flushInterval time.Duration
+	wg            sync.WaitGroup
+	mu            sync.RWMutex
+	closed        bool
+	once          sync.Once
+}
+
+// Options configures the Exporter.
+type Options struct {
+	Endpoint      string        // OTLP/HTTP traces URL, e.g. http://collector:4318/v1/traces
+	ServiceName   string        // service.name resource attribute (default: "go-monitoring")
+	BufferSize    int           // channel capacity          (default: 10 000)
+	BatchSize     int           // spans per export request  (default: 100)
+	FlushInterval time.Duration // max idle time before flush (default: 5 s)
+	Timeout       time.Duration // HTTP request timeout       (default: 10 s)
+}
+
+// New creates an Exporter and starts its background worker.
+// It returns nil when opts.Endpoint is empty.
+func New(opts Options) *Exporter {
+	if opts.Endpoint == "" {
+		return nil
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "go-monitoring"
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 10_000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	e := &Exporter{
+		endpoint:      opts.Endpoint,
+		serviceName:   opts.ServiceName,
+		client:        &http.Client{Timeout: opts.Timeout},
+		ch:            make(chan Span, opts.BufferSize),
+		batchSize:     opts.BatchSize,
+		flushInterval: opts.FlushInterval,
+	}
+
+	e.wg.Add(1)
+	go e.worker()
+
+	return e
+}
+
+// Export enqueues a span. It never blocks: if the buffer is full or the
+// exporter has been shut down, the span is dropped. Calling Export on a
+// nil Exporter is a no-op.
+func (e *Exporter) Export(s Span) {
+	if e == nil {
+		return
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return
+	}
+
+	select {
+	case e.ch <- s:
+	default:
+		log.Println("[go-monitoring] warning: span buffer full, dropping span")
+	}
+}
+
+// Shutdown flushes pending spans and stops the worker.
+// It is safe to call multiple times and on a nil Exporter.
+func (e *Exporter) Shutdown() {
+	if e == nil {
+		return
+	}
+
+	e.once.Do(func() {
+		e.mu.Lock()
+		e.closed = true
+		e.mu.Unlock()
+
+		close(e.ch)
+		e.wg.Wait()
+	})
+}
+
+func (e *Exporter) worker() {
+	defer e.wg.Done()
+
+	batch := make([]Span, 0, e.batchSize)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case s, ok := <-e.ch:
+			if !ok {
+				if len(batch) > 0 {
+					e.flush(batch)
+				}
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= e.batchSize {
+				e.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush posts the batch as a single OTLP ExportTraceServiceRequest.
+func (e *Exporter) flush(batch []Span) {
+	body, err := json.Marshal(e.payload(batch))
+	if err != nil {
+		log.Printf("[go-monitoring] error encoding %d span(s): %v\n", len(batch), err)
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[go-monitoring] error exporting %d span(s): %v\n", len(batch), err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[go-monitoring] error exporting %d span(s): collector returned %s\n", len(batch), resp.Status)
+	}
+}
+
+// --- OTLP JSON encoding ---
+
+type kv struct {
+	Key   string `json:"key"`
+	Value value  `json:"value"`
+}
+
+type value struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64 is encoded as a string in OTLP JSON
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func strAttr(k, v string) kv { return kv{Key: k, Value: value{StringValue: &v}} }
+
+func intAttr(k string, v int) kv {
+	s := strconv.Itoa(v)
+	return kv{Key: k, Value: value{IntValue: &s}}
+}
+
+func floatAttr(k string, v float64) kv { return kv{Key: k, Value: value{DoubleValue: &v}} }
+
+func (e *Exporter) payload(batch []Span) map[string]any {
+	spans := make([]map[string]any, 0, len(batch))
+	for _, s := range batch {
+		// STATUS_CODE_ERROR for server errors, UNSET otherwise.
+		status := map[string]any{}
+		if s.StatusCode >= 500 {
+			status["code"] = 2
+		}
+		spans = append(spans, map[string]any{
+			"traceId":           traceID(s.TraceID),
+			"spanId":            randomHex(8),
+			"name":              s.Method + " " + s.Name,
+			"kind":              2, // SPAN_KIND_SERVER
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes": []kv{
+				strAttr("http.request.method", s.Method),
+				strAttr("http.route", s.Name),
+				intAttr("http.response.status_code", s.StatusCode),
+				floatAttr("http.server.duration_ms", float64(s.End.Sub(s.Start).Microseconds())/1000),
+			},
+			"status": status,
+		})
+	}
+
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": []kv{strAttr("service.name", e.serviceName)},
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "github.com/aghiadodeh/go-monitoring"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// traceID returns id when it is a valid 128-bit hex trace ID, otherwise
+// a freshly generated one. Dashes are dropped first so UUID request IDs,
+// as generated by the middleware, map to the same trace ID.
+func traceID(id string) string {
+	id = strings.ToLower(strings.ReplaceAll(id, "-", ""))
+	if len(id) == 32 && id != strings.Repeat("0", 32) {
+		if _, err := hex.DecodeString(id); err == nil {
+			return id
+		}
+	}
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
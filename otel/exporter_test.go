@@ -0,0 +1,25 @@
+package otel
+
+import "testing"
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"4bf92f3577b34da6a3ce929d0e0e4736", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"4BF92F3577B34DA6A3CE929D0E0E4736", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"4bf92f35-77b3-4da6-a3ce-929d0e0e4736", "4bf92f3577b34da6a3ce929d0e0e4736"},
+	}
+	for _, tt := range tests {
+		if got := traceID(tt.in); got != tt.want {
+			t.Errorf("traceID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "req-42", "00000000-0000-0000-0000-000000000000"} {
+		got := traceID(in)
+		if len(got) != 32 || got == in {
+			t.Errorf("traceID(%q) = %q, want a generated 32-char ID", in, got)
+		}
+	}
+}
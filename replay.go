@@ -0,0 +1,174 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ReplayOptions tunes Monitor.Replay.
+type ReplayOptions struct {
+	// KeepAuthHeaders forwards Authorization, Cookie and similar
+	// credentials, which are stripped by default.
+	KeepAuthHeaders bool
+
+	// Client sends the request (default: a client with a 30s timeout).
+	Client *http.Client
+}
+
+// ErrBodyNotReplayable is returned by Replay when the stored body is a
+// placeholder for a body that was truncated or skipped by content type.
+var ErrBodyNotReplayable = errors.New("monitoring: request body was truncated or not captured, cannot replay")
+
+// hopByHopHeaders are connection-specific and never forwarded.
+var hopByHopHeaders = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-connection":    true,
+	"te":                  true,
+	"trailer":             true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"host":                true,
+	"content-length":      true,
+	"x-monitoring-buffer": true,
+}
+
+// authHeaders carry credentials and are stripped unless
+// ReplayOptions.KeepAuthHeaders is set.
+var authHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+}
+
+// Replay rebuilds the request stored under id (method, path and query,
+// headers, body) and sends it to targetBaseURL, e.g. a staging host, to
+// reproduce a failing production request. Credentials are stripped
+// unless opts says otherwise. Bodies stored as a JSON string or base64
+// are sent in their original form; truncated or skipped bodies cannot
+// be rebuilt and return ErrBodyNotReplayable.
+//
+// The caller must close the response body.
+func (m *Monitor) Replay(id string, targetBaseURL string, opts ...ReplayOptions) (*http.Response, error) {
+	var o ReplayOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	ctx := context.Background()
+	entry, err := m.reqService.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored struct {
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	}
+	if err := json.Unmarshal(entry.Request, &stored); err != nil {
+		return nil, err
+	}
+
+	target, err := replayURL(targetBaseURL, entry.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := replayBody(stored.Body, headerValue(stored.Headers, "Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, entry.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range stored.Headers {
+		lk := strings.ToLower(k)
+		if hopByHopHeaders[lk] || (authHeaders[lk] && !o.KeepAuthHeaders) {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	return o.Client.Do(req)
+}
+
+// replayBody reverses the encoding the capture middleware applies to
+// bodies that are not JSON: a JSON string is sent unquoted, unless the
+// request really was a JSON string, and {"_base64":"..."} is decoded.
+func replayBody(raw json.RawMessage, contentType string) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	switch raw[0] {
+	case '"':
+		if strings.Contains(strings.ToLower(contentType), "json") {
+			return raw, nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	case '{':
+		var env map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &env); err != nil {
+			return nil, err
+		}
+		if _, ok := env["_truncated"]; ok {
+			return nil, ErrBodyNotReplayable
+		}
+		if _, ok := env["_skipped"]; ok {
+			return nil, ErrBodyNotReplayable
+		}
+		if encoded, ok := env["_base64"]; ok && len(env) == 1 {
+			var s string
+			if err := json.Unmarshal(encoded, &s); err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.DecodeString(s)
+		}
+	}
+	return raw, nil
+}
+
+// headerValue looks up name in stored headers case-insensitively.
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// replayURL joins the path and query of the stored URL onto base.
+func replayURL(base, stored string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if b.Scheme == "" || b.Host == "" {
+		return "", errors.New("monitoring: replay target must be an absolute URL, e.g. https://staging.example.com")
+	}
+	s, err := url.Parse(stored)
+	if err != nil {
+		return "", err
+	}
+	b.Path = strings.TrimSuffix(b.Path, "/") + s.Path
+	b.RawPath = ""
+	b.RawQuery = s.RawQuery
+	return b.String(), nil
+}
@@ -0,0 +1,37 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestReplayBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		stored      string
+		contentType string
+		want        string
+		wantErr     error
+	}{
+		{"none", ``, "", "", nil},
+		{"null", `null`, "", "", nil},
+		{"json", `{"a":1}`, "application/json", `{"a":1}`, nil},
+		{"form post", `"a=1&b=2"`, "application/x-www-form-urlencoded", "a=1&b=2", nil},
+		{"json string", `"a=1"`, "application/json", `"a=1"`, nil},
+		{"binary", `{"_base64":"AAFi"}`, "application/octet-stream", "\x00\x01b", nil},
+		{"truncated", `{"_truncated":true,"_originalSize":9,"body":"abc"}`, "text/plain", "", ErrBodyNotReplayable},
+		{"skipped", `{"_skipped":"content-type"}`, "image/png", "", ErrBodyNotReplayable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replayBody(json.RawMessage(tt.stored), tt.contentType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("replayBody() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("replayBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
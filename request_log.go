@@ -0,0 +1,48 @@
+package monitoring
+
+import (
+	"github.com/aghiadodeh/go-monitoring/core"
+	"github.com/aghiadodeh/go-monitoring/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestLogInput describes a request handled outside the Fiber
+// middleware (gRPC gateways, outbound HTTP calls, other frameworks).
+// Only Method, Path and StatusCode are required.
+type RequestLogInput = core.RequestLogInput
+
+// LogRequest enqueues a request log built from in. Like the middleware it
+// never blocks; the entry is dropped if the writer buffer is full.
+func (m *Monitor) LogRequest(in RequestLogInput) {
+	m.recorder.Record(in)
+}
+
+// Recorder returns the framework-independent recorder used by the Fiber
+// middleware, for building adapters for other HTTP frameworks.
+func (m *Monitor) Recorder() *core.Recorder {
+	return m.recorder
+}
+
+// StartSpan starts timing a named section of the current request handler,
+// e.g. a database query. Call End on the returned span when the section
+// finishes; the accumulated durations are stored in the log's timings.
+//
+//	span := m.StartSpan(c, "db")
+//	err := db.Find(&users).Error
+//	span.End()
+func (m *Monitor) StartSpan(c *fiber.Ctx, name string) *middleware.Span {
+	return middleware.StartSpan(c, name)
+}
+
+// Timings returns a goroutine-safe handle for starting spans of the
+// current request from goroutines the handler spawns, since fiber.Ctx
+// must not be used there. Call it before spawning them.
+//
+//	timer := m.Timings(c)
+//	go func() {
+//		defer timer.StartSpan("cache").End()
+//		// ...
+//	}()
+func (m *Monitor) Timings(c *fiber.Ctx) *middleware.Timer {
+	return middleware.Timings(c)
+}
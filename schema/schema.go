@@ -0,0 +1,323 @@
+// Package schema validates JSON values against a JSON Schema. It has no
+// third-party dependencies and supports the subset of keywords useful for
+// describing job metadata:
+//
+//	type (string or array), enum, const,
+//	properties, required, additionalProperties (bool or schema),
+//	items, minItems, maxItems,
+//	minimum, maximum, minLength, maxLength, pattern.
+//
+// Annotations such as title, description and $schema are allowed. Compile
+// rejects any other keyword (e.g. $ref, oneOf, format), so a schema
+// never silently accepts values it was written to refuse.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	types      []string
+	enum       []any
+	constVal   *any
+	properties map[string]*Schema
+	required   []string
+	additional *Schema // nil = any additional property allowed
+	noExtra    bool    // additionalProperties: false
+	items      *Schema
+	minItems   *int
+	maxItems   *int
+	minimum    *float64
+	maximum    *float64
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+}
+
+// raw mirrors the supported keywords for decoding.
+type raw struct {
+	Type                 json.RawMessage            `json:"type"`
+	Enum                 []any                      `json:"enum"`
+	Const                *json.RawMessage           `json:"const"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              string                     `json:"pattern"`
+}
+
+// keywords lists the keywords Compile accepts: the supported validation
+// keywords and annotations that do not affect validation.
+var keywords = map[string]bool{
+	"type": true, "enum": true, "const": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minimum": true, "maximum": true, "minLength": true, "maxLength": true,
+	"pattern": true,
+
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// Compile parses a JSON Schema document. It returns an error for
+// keywords it does not support.
+func Compile(doc []byte) (*Schema, error) {
+	return compile(doc, "#")
+}
+
+func compile(doc []byte, at string) (*Schema, error) {
+	// Boolean schemas: true accepts anything, false nothing.
+	switch strings.TrimSpace(string(doc)) {
+	case "true":
+		return &Schema{}, nil
+	case "false":
+		return &Schema{types: []string{}}, nil
+	}
+
+	var r raw
+	if err := json.Unmarshal(doc, &r); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", at, err)
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &all); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", at, err)
+	}
+	var unsupported []string
+	for k := range all {
+		if !keywords[k] {
+			unsupported = append(unsupported, k)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, fmt.Errorf("schema %s: unsupported keyword(s) %s", at, strings.Join(unsupported, ", "))
+	}
+
+	s := &Schema{
+		enum:      r.Enum,
+		required:  r.Required,
+		minItems:  r.MinItems,
+		maxItems:  r.MaxItems,
+		minimum:   r.Minimum,
+		maximum:   r.Maximum,
+		minLength: r.MinLength,
+		maxLength: r.MaxLength,
+	}
+
+	if len(r.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(r.Type, &one); err == nil {
+			s.types = []string{one}
+		} else if err := json.Unmarshal(r.Type, &s.types); err != nil {
+			return nil, fmt.Errorf("schema %s/type: must be a string or array of strings", at)
+		}
+	}
+	if r.Const != nil {
+		var v any
+		if err := json.Unmarshal(*r.Const, &v); err != nil {
+			return nil, fmt.Errorf("schema %s/const: %w", at, err)
+		}
+		s.constVal = &v
+	}
+	if len(r.Properties) > 0 {
+		s.properties = make(map[string]*Schema, len(r.Properties))
+		for name, sub := range r.Properties {
+			p, err := compile(sub, at+"/properties/"+name)
+			if err != nil {
+				return nil, err
+			}
+			s.properties[name] = p
+		}
+	}
+	if len(r.AdditionalProperties) > 0 {
+		switch strings.TrimSpace(string(r.AdditionalProperties)) {
+		case "false":
+			s.noExtra = true
+		case "true":
+		default:
+			p, err := compile(r.AdditionalProperties, at+"/additionalProperties")
+			if err != nil {
+				return nil, err
+			}
+			s.additional = p
+		}
+	}
+	if len(r.Items) > 0 {
+		p, err := compile(r.Items, at+"/items")
+		if err != nil {
+			return nil, err
+		}
+		s.items = p
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s/pattern: %w", at, err)
+		}
+		s.pattern = re
+	}
+	return s, nil
+}
+
+// ValidateJSON decodes doc and validates it against s.
+func (s *Schema) ValidateJSON(doc []byte) error {
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return err
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a decoded JSON value (as produced by json.Unmarshal into
+// any) against s. The error names the offending location, e.g.
+// `$.retries: expected integer, got string`.
+func (s *Schema) Validate(v any) error {
+	return s.validate(v, "$")
+}
+
+func (s *Schema) validate(v any, at string) error {
+	if s.types != nil && !matchesType(v, s.types) {
+		if len(s.types) == 0 {
+			return fmt.Errorf("%s: no value is allowed here", at)
+		}
+		return fmt.Errorf("%s: expected %s, got %s", at, strings.Join(s.types, " or "), typeOf(v))
+	}
+	if s.constVal != nil && !reflect.DeepEqual(v, *s.constVal) {
+		return fmt.Errorf("%s: must equal %v", at, *s.constVal)
+	}
+	if len(s.enum) > 0 {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: must be one of %v", at, s.enum)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		return s.validateObject(val, at)
+	case []any:
+		return s.validateArray(val, at)
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.minLength != nil && n < *s.minLength {
+			return fmt.Errorf("%s: length must be at least %d", at, *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			return fmt.Errorf("%s: length must be at most %d", at, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			return fmt.Errorf("%s: must match pattern %q", at, s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && val < *s.minimum {
+			return fmt.Errorf("%s: must be >= %v", at, *s.minimum)
+		}
+		if s.maximum != nil && val > *s.maximum {
+			return fmt.Errorf("%s: must be <= %v", at, *s.maximum)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(obj map[string]any, at string) error {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", at, name)
+		}
+	}
+
+	// Sorted for deterministic error messages.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sub, ok := s.properties[k]
+		switch {
+		case ok:
+		case s.noExtra:
+			return fmt.Errorf("%s: unexpected property %q", at, k)
+		case s.additional != nil:
+			sub = s.additional
+		default:
+			continue
+		}
+		if err := sub.validate(obj[k], at+"."+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(arr []any, at string) error {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		return fmt.Errorf("%s: must have at least %d item(s)", at, *s.minItems)
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		return fmt.Errorf("%s: must have at most %d item(s)", at, *s.maxItems)
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			if err := s.items.validate(item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesType(v any, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a decoded JSON value.
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
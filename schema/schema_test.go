@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileRejectsUnsupportedKeywords(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{"top level", `{"type":"object","oneOf":[{}]}`, "schema #: unsupported keyword(s) oneOf"},
+		{"nested", `{"properties":{"id":{"type":"string","format":"uuid"}}}`, "schema #/properties/id: unsupported keyword(s) format"},
+		{"items", `{"items":{"$ref":"#/defs/x"}}`, "schema #/items: unsupported keyword(s) $ref"},
+		{"sorted", `{"anyOf":[],"allOf":[]}`, "schema #: unsupported keyword(s) allOf, anyOf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileAllowsAnnotations(t *testing.T) {
+	doc := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Sync result",
+		"description": "Metadata of the sync job",
+		"type": "object",
+		"properties": {"count": {"type": "integer", "minimum": 0, "default": 0}},
+		"required": ["count"]
+	}`
+	s, err := Compile([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ValidateJSON([]byte(`{"count": 3}`)); err != nil {
+		t.Errorf("valid metadata rejected: %v", err)
+	}
+	if err := s.ValidateJSON([]byte(`{"count": -1}`)); err == nil {
+		t.Error("metadata below minimum accepted")
+	}
+	if err := s.ValidateJSON([]byte(`{}`)); err == nil {
+		t.Error("metadata without required property accepted")
+	}
+}
+
+func TestCompileBooleanSchemas(t *testing.T) {
+	for doc, valid := range map[string]bool{"true": true, "false": false} {
+		s, err := Compile([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ValidateJSON([]byte(`{"a":1}`)); (err == nil) != valid {
+			t.Errorf("schema %s: ValidateJSON() error = %v", doc, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+)
+
+// Cache indicators reported in AnalyzeResult.Cache.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// analyzeCache is an in-memory TTL cache of Analyze results keyed by the
+// normalized filter.
+type analyzeCache struct {
+	mu      sync.Mutex
+	entries map[string]analyzeCacheEntry
+}
+
+type analyzeCacheEntry struct {
+	result    *AnalyzeResult
+	expiresAt time.Time
+}
+
+// get returns a copy of the cached result for key if it has not expired.
+func (c *analyzeCache) get(key string) (*AnalyzeResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	r := *e.result
+	return &r, true
+}
+
+// set stores a copy of r under key, evicting expired entries so the map
+// stays bounded by the number of distinct filters polled within ttl.
+func (c *analyzeCache) set(key string, r *AnalyzeResult, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.entries == nil {
+		c.entries = make(map[string]analyzeCacheEntry)
+	}
+	for k, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	cp := *r
+	c.entries[key] = analyzeCacheEntry{result: &cp, expiresAt: now.Add(ttl)}
+}
+
+// clear drops every cached result.
+func (c *analyzeCache) clear() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
+
+// analyzeCacheKey normalizes f into a cache key. Open-ended date ranges
+// use the raw (empty) values, so a cached "last 24h" result is served
+// until it expires rather than missing on every call.
+func analyzeCacheKey(f dto.AnalyzeOptions) string {
+	return strings.Join([]string{
+		f.FromDate,
+		f.ToDate,
+		f.Method,
+		f.URL,
+		strconv.FormatBool(f.SuccessOnly),
+		strings.ToLower(f.DurationUnit),
+		strconv.Itoa(f.MaxBucketItems),
+		f.TZ,
+		f.Granularity,
+	}, "\x00")
+}
+
+// InvalidateAnalyzeCache drops all cached Analyze results and facets. It
+// is called after logs are cleared or archived.
+func (s *RequestService) InvalidateAnalyzeCache() {
+	s.cache.clear()
+	s.facets.clear()
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// blockingPool fails every query, but only once release is closed; the
+// first query closes entered.
+type blockingPool struct {
+	entered     chan struct{}
+	enteredOnce sync.Once
+	release     chan struct{}
+}
+
+var errNoDB = errors.New("no database")
+
+func (p *blockingPool) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	p.enteredOnce.Do(func() { close(p.entered) })
+	<-p.release
+	return nil, errNoDB
+}
+
+func (p *blockingPool) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return nil, errNoDB
+}
+
+func (p *blockingPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errNoDB
+}
+
+func (p *blockingPool) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
+
+func TestAnalyzeBusy(t *testing.T) {
+	pool := &blockingPool{entered: make(chan struct{}), release: make(chan struct{})}
+	db, err := gorm.Open(testDialector{pool: pool}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const limit = 2
+	s := &RequestService{DB: db, MaxConcurrentAnalyze: limit}
+
+	// Fill every slot with an analysis stuck on the database.
+	var running sync.WaitGroup
+	for range limit {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			_, _ = s.Analyze(context.Background(), dto.AnalyzeOptions{})
+		}()
+	}
+	<-pool.entered
+	for len(s.analyzeSem) < limit {
+		// Wait until the second call holds its slot too.
+		runtime.Gosched()
+	}
+
+	var busy atomic.Int32
+	var rejected sync.WaitGroup
+	for range 8 {
+		rejected.Add(1)
+		go func() {
+			defer rejected.Done()
+			if _, err := s.Analyze(context.Background(), dto.AnalyzeOptions{}); errors.Is(err, ErrAnalyzeBusy) {
+				busy.Add(1)
+			}
+		}()
+	}
+	rejected.Wait()
+	if got := busy.Load(); got != 8 {
+		t.Errorf("%d calls got ErrAnalyzeBusy, want 8", got)
+	}
+
+	close(pool.release)
+	running.Wait()
+	if _, err := s.Analyze(context.Background(), dto.AnalyzeOptions{}); errors.Is(err, ErrAnalyzeBusy) {
+		t.Error("Analyze still busy after the running calls finished")
+	}
+}
+
+func TestAnalyzeErrorNotCached(t *testing.T) {
+	pool := &blockingPool{entered: make(chan struct{}), release: make(chan struct{})}
+	close(pool.release)
+	db, err := gorm.Open(testDialector{pool: pool}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &RequestService{DB: db, AnalyzeCacheTTL: time.Minute}
+
+	if r, err := s.Analyze(context.Background(), dto.AnalyzeOptions{}); !errors.Is(err, errNoDB) {
+		t.Fatalf("Analyze() = %+v, %v, want errNoDB", r, err)
+	}
+	if n := len(s.cache.entries); n != 0 {
+		t.Errorf("cache holds %d entries after a failed query, want 0", n)
+	}
+}
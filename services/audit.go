@@ -0,0 +1,21 @@
+package services
+
+import "context"
+
+// AuditJobName is the job name under which dashboard actions (logins,
+// clears) are recorded. List them with GET /jobs?name=monitoring.audit.
+const AuditJobName = "monitoring.audit"
+
+// AuditEntry is the metadata stored for one audited action.
+type AuditEntry struct {
+	Action string `json:"action"` // e.g. "login", "clear"
+	User   string `json:"user"`
+	IP     string `json:"ip"`
+	Detail any    `json:"detail,omitempty"`
+}
+
+// Audit records a dashboard action as a job log named AuditJobName.
+// success is false for rejected attempts such as failed logins.
+func (s *JobService) Audit(ctx context.Context, success bool, e AuditEntry) error {
+	return s.Create(ctx, AuditJobName, success, e)
+}
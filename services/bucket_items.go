@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+	"github.com/aghiadodeh/go-monitoring/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidBoundary is returned by BucketItems for a boundary that does
+// not start a duration bucket.
+var ErrInvalidBoundary = errors.New("monitoring: invalid bucket boundary")
+
+// BucketItems pages through the requests of a single Analyze duration
+// bucket, identified by its lower boundary in ms (one of
+// DurationBoundaries except the last). f supplies the date range and
+// per_page; page starts at 1. Items are ordered slowest first.
+func (s *RequestService) BucketItems(ctx context.Context, boundary float64, f dto.BaseFilter, page int) (*dto.ListResponse[DurationBucketItem], error) {
+	lower, upper, ok := bucketRange(boundary)
+	if !ok {
+		return nil, fmt.Errorf("%w: must be one of %v", ErrInvalidBoundary, DurationBoundaries[:len(DurationBoundaries)-1])
+	}
+	if page < 1 {
+		page = 1
+	}
+	f.Page = strconv.Itoa(page)
+	perPage, skip := pagination(f)
+
+	from, to := parseDateRange(f, s.DefaultLookback)
+	q := s.DB.WithContext(ctx).Model(&models.RequestLog{}).
+		Scopes(DateRangeScope(from, to), s.excludeFromAnalytics).
+		Where("duration >= ? AND duration < ?", lower, upper)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []models.RequestLog
+	err := q.Session(&gorm.Session{}).
+		Select("path", "url", "method", "success", "duration").
+		Order("duration DESC").Offset(skip).Limit(perPage).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]DurationBucketItem, len(rows))
+	for i, r := range rows {
+		url := r.Path
+		if url == "" {
+			url = r.URL
+		}
+		items[i] = DurationBucketItem{Duration: r.Duration, URL: url, Method: r.Method, Success: r.Success}
+	}
+	return dto.NewListResponse(items, total, page, perPage), nil
+}
+
+// bucketRange returns the [lower, upper) edges of the duration bucket
+// starting at boundary.
+func bucketRange(boundary float64) (lower, upper float64, ok bool) {
+	for i := 0; i+1 < len(DurationBoundaries); i++ {
+		if DurationBoundaries[i] == boundary {
+			return DurationBoundaries[i], DurationBoundaries[i+1], true
+		}
+	}
+	return 0, 0, false
+}
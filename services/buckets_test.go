@@ -0,0 +1,66 @@
+package services
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+)
+
+func TestBucketDurations(t *testing.T) {
+	requests := []models.RequestLog{
+		{Duration: 0, Path: "/a"},
+		{Duration: 9.9, Path: "/b"},
+		{Duration: 10, URL: "http://h/c"},
+		{Duration: 50},
+		{Duration: 100}, // past the last boundary
+		{Duration: -1},
+	}
+	buckets := bucketDurations(requests, []float64{0, 10, 100})
+	if len(buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2: %+v", len(buckets), buckets)
+	}
+	if buckets[0].ID != 0 || buckets[0].Count != 2 {
+		t.Errorf("bucket 0 = %+v, want ID 0 with 2 requests", buckets[0])
+	}
+	if buckets[1].ID != 10 || buckets[1].Count != 2 || buckets[1].Data[0].URL != "http://h/c" {
+		t.Errorf("bucket 1 = %+v, want ID 10 with 2 requests", buckets[1])
+	}
+}
+
+func TestMethodCountsUseMethodGroups(t *testing.T) {
+	s := &RequestService{MethodGroups: map[string]string{"PUT": "WRITE", "PATCH": "WRITE"}}
+	requests := []models.RequestLog{
+		{Method: "GET", Success: true},
+		{Method: "PUT", Success: true},
+		{Method: "PATCH"},
+		{Method: "patch", Success: true},
+	}
+	got := s.methodCounts(requests)
+	want := []MethodCount{
+		{Method: "WRITE", Count: 3, SuccessCount: 2},
+		{Method: "GET", Count: 1, SuccessCount: 1},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("methodCounts() = %+v, want %+v", got, want)
+	}
+}
+
+func BenchmarkBucketDurations(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	requests := make([]models.RequestLog, 100_000)
+	for i := range requests {
+		requests[i] = models.RequestLog{
+			Duration: rng.ExpFloat64() * 200,
+			Path:     "/api/users/:id",
+			Method:   "GET",
+			Success:  true,
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		bucketDurations(requests, DurationBoundaries)
+	}
+}
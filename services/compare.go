@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+	"github.com/aghiadodeh/go-monitoring/models"
+)
+
+// RangeStats summarizes the request logs of one date range.
+type RangeStats struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	Total           int64     `json:"total"`
+	Errors          int64     `json:"errors"`
+	ErrorRate       float64   `json:"errorRate"`       // 0–100
+	AverageDuration float64   `json:"averageDuration"` // ms
+}
+
+// CompareResult is returned by Compare. The *Change fields are the
+// percentage change from Previous to Current, or nil when the previous
+// value is zero.
+type CompareResult struct {
+	Current  RangeStats `json:"current"`
+	Previous RangeStats `json:"previous"`
+
+	TotalChange           *float64 `json:"totalChange"`
+	ErrorRateChange       *float64 `json:"errorRateChange"`
+	AverageDurationChange *float64 `json:"averageDurationChange"`
+}
+
+// previousRange returns the range previous selects for comparison with
+// [from, to), filling in a missing end with the current range's length.
+func previousRange(from, to time.Time, previous dto.BaseFilter) (time.Time, time.Time) {
+	length := to.Sub(from)
+	switch {
+	case previous.FromDate == "" && previous.ToDate == "":
+		return from.Add(-length), from
+	case previous.FromDate == "":
+		_, prevTo := parseDateRange(previous, length)
+		return prevTo.Add(-length), prevTo
+	case previous.ToDate == "":
+		prevFrom, _ := parseDateRange(previous, length)
+		return prevFrom, prevFrom.Add(length)
+	default:
+		return parseDateRange(previous, length)
+	}
+}
+
+// Compare returns totals, error rates and average durations for two date
+// ranges (e.g. this week vs last week) plus the percentage deltas. When
+// previous has neither fromDate nor toDate, it defaults to the window of
+// equal length immediately preceding current. When it has only one of
+// them, the window of equal length starting or ending there is used.
+func (s *RequestService) Compare(ctx context.Context, current, previous dto.BaseFilter) (*CompareResult, error) {
+	from, to := parseDateRange(current, s.DefaultLookback)
+	prevFrom, prevTo := previousRange(from, to, previous)
+
+	cur, err := s.rangeStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := s.rangeStats(ctx, prevFrom, prevTo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompareResult{
+		Current:               cur,
+		Previous:              prev,
+		TotalChange:           percentChange(float64(cur.Total), float64(prev.Total)),
+		ErrorRateChange:       percentChange(cur.ErrorRate, prev.ErrorRate),
+		AverageDurationChange: percentChange(cur.AverageDuration, prev.AverageDuration),
+	}, nil
+}
+
+// rangeStats aggregates the request logs created between from and to.
+func (s *RequestService) rangeStats(ctx context.Context, from, to time.Time) (RangeStats, error) {
+	var row struct {
+		Total       int64
+		Errors      int64
+		AvgDuration float64
+	}
+	err := s.DB.WithContext(ctx).Model(&models.RequestLog{}).
+		Scopes(DateRangeScope(from, to), s.excludeFromAnalytics).
+		Select("COUNT(*) AS total, " +
+			"COALESCE(SUM(CASE WHEN success THEN 0 ELSE 1 END), 0) AS errors, " +
+			"COALESCE(AVG(duration), 0) AS avg_duration").
+		Scan(&row).Error
+	if err != nil {
+		return RangeStats{}, err
+	}
+
+	stats := RangeStats{
+		From:            from,
+		To:              to,
+		Total:           row.Total,
+		Errors:          row.Errors,
+		AverageDuration: row.AvgDuration,
+	}
+	if row.Total > 0 {
+		stats.ErrorRate = float64(row.Errors) / float64(row.Total) * 100
+	}
+	return stats, nil
+}
+
+// percentChange returns the change from prev to cur in percent, or nil
+// when prev is zero.
+func percentChange(cur, prev float64) *float64 {
+	if prev == 0 {
+		return nil
+	}
+	v := (cur - prev) / prev * 100
+	return &v
+}
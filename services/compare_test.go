@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+)
+
+func TestPreviousRange(t *testing.T) {
+	from := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name             string
+		previous         dto.BaseFilter
+		wantFrom, wantTo time.Time
+	}{
+		{"default", dto.BaseFilter{}, day(2), day(9)},
+		{"only toDate", dto.BaseFilter{ToDate: "2026-03-05T00:00:00Z"}, time.Date(2026, 2, 26, 0, 0, 0, 0, time.UTC), day(5)},
+		{"only fromDate", dto.BaseFilter{FromDate: "2026-03-01T00:00:00Z"}, day(1), day(8)},
+		{"both", dto.BaseFilter{FromDate: "2026-03-01T00:00:00Z", ToDate: "2026-03-02T00:00:00Z"}, day(1), day(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFrom, gotTo := previousRange(from, to, tt.previous)
+			if !gotFrom.Equal(tt.wantFrom) || !gotTo.Equal(tt.wantTo) {
+				t.Errorf("previousRange() = %v – %v, want %v – %v", gotFrom, gotTo, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Europe/Berlin without system zoneinfo
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+)
+
+func TestParseDateRange(t *testing.T) {
+	from, to := parseDateRange(dto.BaseFilter{
+		FromDate: "2026-03-28T23:00:00+01:00",
+		ToDate:   "2026-03-30T00:00:00+02:00",
+	}, 0)
+	if want := time.Date(2026, 3, 28, 22, 0, 0, 0, time.UTC); !from.Equal(want) || from.Location() != time.UTC {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	if want := time.Date(2026, 3, 29, 22, 0, 0, 0, time.UTC); !to.Equal(want) || to.Location() != time.UTC {
+		t.Errorf("to = %v, want %v", to, want)
+	}
+
+	// Missing or invalid dates fall back to the lookback window.
+	from, to = parseDateRange(dto.BaseFilter{FromDate: "yesterday"}, time.Hour)
+	if d := to.Sub(from); d != time.Hour {
+		t.Errorf("fallback range = %v, want 1h", d)
+	}
+	from, to = parseDateRange(dto.BaseFilter{}, 0)
+	if d := to.Sub(from); d != defaultLookback {
+		t.Errorf("default range = %v, want %v", d, defaultLookback)
+	}
+}
+
+func TestBuildTimeRangeAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks move forward on 2026-03-29 and back on 2026-10-25 in Berlin.
+	tests := []struct {
+		name     string
+		from, to time.Time
+		hours    []float64 // length of each bucket, the last one ending at to
+	}{
+		{
+			"spring forward",
+			time.Date(2026, 3, 27, 23, 0, 0, 0, time.UTC), // 28 Mar 00:00 CET
+			time.Date(2026, 3, 30, 22, 0, 0, 0, time.UTC), // 31 Mar 00:00 CEST
+			[]float64{24, 23, 24},
+		},
+		{
+			"fall back",
+			time.Date(2026, 10, 23, 22, 0, 0, 0, time.UTC), // 24 Oct 00:00 CEST
+			time.Date(2026, 10, 26, 23, 0, 0, 0, time.UTC), // 27 Oct 00:00 CET
+			[]float64{24, 25, 24},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := buildTimeRange(tt.from, tt.to, berlin, GranularityDay)
+			if len(r) != len(tt.hours) {
+				t.Fatalf("got %d buckets, want %d: %v", len(r), len(tt.hours), r)
+			}
+			for i, b := range r {
+				if local := b.In(berlin); local.Hour() != 0 || local.Minute() != 0 {
+					t.Errorf("bucket %d starts at %v, want local midnight", i, local)
+				}
+			}
+			bounds := append(r, tt.to)
+			for i, want := range tt.hours {
+				if got := bounds[i+1].Sub(bounds[i]).Hours(); got != want {
+					t.Errorf("bucket %d spans %vh, want %vh", i, got, want)
+				}
+			}
+		})
+	}
+
+	// Without a zone, buckets are fixed 24h steps in UTC.
+	r := buildTimeRange(tests[0].from, tests[0].to, nil, GranularityDay)
+	for i := 1; i < len(r)-1; i++ {
+		if d := r[i].Sub(r[i-1]); d != 24*time.Hour {
+			t.Errorf("UTC bucket %d spans %v, want 24h", i-1, d)
+		}
+	}
+}
+
+func TestAlignTimeWeekAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wednesday after the spring change aligns to Monday midnight before it.
+	got := alignTime(time.Date(2026, 4, 1, 12, 0, 0, 0, berlin), GranularityWeek)
+	if want := time.Date(2026, 3, 30, 0, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("alignTime = %v, want %v", got, want)
+	}
+}
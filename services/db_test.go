@@ -0,0 +1,42 @@
+package services
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// testDialector runs gorm without a real database: statements go to
+// pool, or are only built when pool is nil (for DryRun sessions).
+type testDialector struct {
+	pool gorm.ConnPool
+}
+
+func (testDialector) Name() string { return "dryrun" }
+
+func (d testDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	if d.pool != nil {
+		db.ConnPool = d.pool
+	}
+	return nil
+}
+
+func (testDialector) Migrator(*gorm.DB) gorm.Migrator { return nil }
+
+func (testDialector) DataTypeOf(*schema.Field) string { return "" }
+
+func (testDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (testDialector) BindVarTo(w clause.Writer, _ *gorm.Statement, _ any) {
+	_ = w.WriteByte('?')
+}
+
+func (testDialector) QuoteTo(w clause.Writer, s string) {
+	_, _ = w.WriteString(`"` + s + `"`)
+}
+
+func (testDialector) Explain(sql string, _ ...any) string { return sql }
@@ -0,0 +1,49 @@
+package services
+
+import "fmt"
+
+// Supported values for the durationUnit query parameter. Durations are
+// always stored in milliseconds; the unit only affects API responses.
+const (
+	DurationMillis = "ms"
+	DurationSecs   = "s"
+	DurationMicros = "us"
+)
+
+// DurationScale returns the factor that converts stored milliseconds to
+// unit. An empty unit means milliseconds.
+func DurationScale(unit string) (float64, error) {
+	switch unit {
+	case "", DurationMillis:
+		return 1, nil
+	case DurationSecs:
+		return 0.001, nil
+	case DurationMicros:
+		return 1000, nil
+	default:
+		return 0, fmt.Errorf("monitoring: unsupported duration unit %q (use ms, s or us)", unit)
+	}
+}
+
+// scaleDurations converts every duration in r from milliseconds by factor.
+func (r *AnalyzeResult) scaleDurations(factor float64) {
+	if factor == 1 {
+		return
+	}
+	for i := range r.Duration {
+		r.Duration[i].ID *= factor
+		for j := range r.Duration[i].Data {
+			r.Duration[i].Data[j].Duration *= factor
+		}
+	}
+	for i := range r.DurationURLs {
+		r.DurationURLs[i].Min *= factor
+		r.DurationURLs[i].Max *= factor
+		r.DurationURLs[i].Average *= factor
+	}
+	scaled := make([]float64, len(r.DurationBoundaries))
+	for i, b := range r.DurationBoundaries {
+		scaled[i] = b * factor
+	}
+	r.DurationBoundaries = scaled
+}
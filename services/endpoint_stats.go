@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+)
+
+// EndpointStats aggregates request counts, errors and durations for a
+// single endpoint (route path + method).
+type EndpointStats struct {
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"` // 0–100
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Average   float64 `json:"average"`
+}
+
+// AnalyzeByEndpoint returns per-endpoint stats for the date range,
+// optionally restricted to problem endpoints via MinErrorRate and
+// MinCount. Results are ordered by error rate, then request count.
+func (s *RequestService) AnalyzeByEndpoint(ctx context.Context, f dto.EndpointFilter) ([]EndpointStats, error) {
+	scale, err := DurationScale(f.DurationUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := parseDateRange(f.BaseFilter, s.DefaultLookback)
+
+	var rows []struct {
+		Path        string
+		Method      string
+		Total       int64
+		Errors      int64
+		MinDuration float64
+		MaxDuration float64
+		SumDuration float64
+	}
+	err = s.analyzeQuery(ctx, f.AnalyzeOptions, from, to).
+		Select("path, method, COUNT(*) AS total, " +
+			"SUM(CASE WHEN success THEN 0 ELSE 1 END) AS errors, " +
+			"MIN(duration) AS min_duration, MAX(duration) AS max_duration, SUM(duration) AS sum_duration").
+		Group("path, method").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Fold grouped methods (e.g. HEAD → GET) into a single entry.
+	type key struct{ path, method string }
+	type acc struct {
+		count, errors int64
+		min, max, sum float64
+	}
+	merged := make(map[key]*acc)
+	for _, r := range rows {
+		k := key{path: r.Path, method: s.groupMethod(r.Method)}
+		a, ok := merged[k]
+		if !ok {
+			merged[k] = &acc{count: r.Total, errors: r.Errors, min: r.MinDuration, max: r.MaxDuration, sum: r.SumDuration}
+			continue
+		}
+		a.count += r.Total
+		a.errors += r.Errors
+		a.min = math.Min(a.min, r.MinDuration)
+		a.max = math.Max(a.max, r.MaxDuration)
+		a.sum += r.SumDuration
+	}
+
+	stats := make([]EndpointStats, 0, len(merged))
+	for k, a := range merged {
+		rate := float64(a.errors) / float64(a.count) * 100
+		if f.MinCount != nil && a.count < *f.MinCount {
+			continue
+		}
+		if f.MinErrorRate != nil && rate <= *f.MinErrorRate {
+			continue
+		}
+		stats = append(stats, EndpointStats{
+			Method:    k.method,
+			Path:      k.path,
+			Count:     a.count,
+			Errors:    a.errors,
+			ErrorRate: rate,
+			Min:       a.min * scale,
+			Max:       a.max * scale,
+			Average:   a.sum / float64(a.count) * scale,
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].ErrorRate != stats[j].ErrorRate {
+			return stats[i].ErrorRate > stats[j].ErrorRate
+		}
+		return stats[i].Count > stats[j].Count
+	})
+	return stats, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+	"github.com/aghiadodeh/go-monitoring/models"
+)
+
+// facetsTTL is how long Facets results are reused; the dashboard asks for
+// them on every filter panel render.
+const facetsTTL = 30 * time.Second
+
+// Facets are the distinct values available for the dashboard's filter
+// dropdowns within a date range.
+type Facets struct {
+	Methods     []string `json:"methods"`
+	StatusCodes []int    `json:"statusCodes"`
+	JobNames    []string `json:"jobNames"`
+}
+
+// facetsCache keeps Facets results per date range for facetsTTL.
+type facetsCache struct {
+	mu      sync.Mutex
+	entries map[string]facetsCacheEntry
+}
+
+type facetsCacheEntry struct {
+	facets    *Facets
+	expiresAt time.Time
+}
+
+func (c *facetsCache) get(key string) (*Facets, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+	return e.facets, true
+}
+
+func (c *facetsCache) set(key string, f *Facets) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.entries == nil {
+		c.entries = make(map[string]facetsCacheEntry)
+	}
+	for k, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = facetsCacheEntry{facets: f, expiresAt: now.Add(facetsTTL)}
+}
+
+func (c *facetsCache) clear() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
+
+// Facets returns the distinct request methods, response status codes and
+// job names logged within the filter's date range, sorted. Results are
+// cached briefly. The returned value must not be modified.
+func (s *RequestService) Facets(ctx context.Context, f dto.BaseFilter) (*Facets, error) {
+	key := f.FromDate + "\x00" + f.ToDate
+	if cached, ok := s.facets.get(key); ok {
+		return cached, nil
+	}
+
+	from, to := parseDateRange(f, s.DefaultLookback)
+	db := s.DB.WithContext(ctx)
+	result := &Facets{Methods: []string{}, StatusCodes: []int{}, JobNames: []string{}}
+
+	err := db.Model(&models.RequestLog{}).Scopes(DateRangeScope(from, to)).
+		Distinct("method").Order("method").Pluck("method", &result.Methods).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = db.Model(&models.RequestLog{}).Scopes(DateRangeScope(from, to)).
+		Distinct().Pluck("response->>'statusCode'", &codes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range codes {
+		if n, err := strconv.Atoi(c); err == nil {
+			result.StatusCodes = append(result.StatusCodes, n)
+		}
+	}
+	sort.Ints(result.StatusCodes)
+
+	err = db.Model(&models.JobLog{}).Scopes(DateRangeScope(from, to)).
+		Distinct("name").Order("name").Pluck("name", &result.JobNames).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.facets.set(key, result)
+	return result, nil
+}
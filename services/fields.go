@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+	"github.com/aghiadodeh/go-monitoring/models"
+)
+
+// selectableField is a RequestLog column that may be requested with
+// ?fields=, keyed by its JSON name.
+type selectableField struct {
+	column string
+	value  func(r *models.RequestLog) any
+}
+
+var selectableFields = map[string]selectableField{
+	"id":              {"id", func(r *models.RequestLog) any { return r.ID }},
+	"key":             {"key", func(r *models.RequestLog) any { return r.Key }},
+	"path":            {"path", func(r *models.RequestLog) any { return r.Path }},
+	"url":             {"url", func(r *models.RequestLog) any { return r.URL }},
+	"method":          {"method", func(r *models.RequestLog) any { return r.Method }},
+	"user":            {"user", func(r *models.RequestLog) any { return r.User }},
+	"request":         {"request", func(r *models.RequestLog) any { return r.Request }},
+	"response":        {"response", func(r *models.RequestLog) any { return r.Response }},
+	"responseHeaders": {"response_headers", func(r *models.RequestLog) any { return r.ResponseHeaders }},
+	"routeParams":     {"route_params", func(r *models.RequestLog) any { return r.RouteParams }},
+	"timings":         {"timings", func(r *models.RequestLog) any { return r.Timings }},
+	"tags":            {"tags", func(r *models.RequestLog) any { return r.Tags }},
+	"indexedFields":   {"indexed_fields", func(r *models.RequestLog) any { return r.IndexedFields }},
+	"success":         {"success", func(r *models.RequestLog) any { return r.Success }},
+	"duration":        {"duration", func(r *models.RequestLog) any { return r.Duration }},
+	"traceId":         {"trace_id", func(r *models.RequestLog) any { return r.TraceID }},
+	"createdAt":       {"created_at", func(r *models.RequestLog) any { return r.CreatedAt }},
+	"updatedAt":       {"updated_at", func(r *models.RequestLog) any { return r.UpdatedAt }},
+}
+
+// ParseFields parses a comma-separated ?fields= value into RequestLog
+// JSON field names, rejecting names that are not selectable.
+func ParseFields(s string) ([]string, error) {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := selectableFields[name]; !ok {
+			return nil, fmt.Errorf("monitoring: unknown field %q in fields", name)
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("monitoring: fields must name at least one field")
+	}
+	return fields, nil
+}
+
+// FindAllFields is FindAll restricted to the given fields (JSON names,
+// see ParseFields). Only those columns are loaded, and each row is
+// returned as a map holding just those keys.
+func (s *RequestService) FindAllFields(ctx context.Context, f dto.RequestFilter, fields []string) (*dto.ListResponse[map[string]any], error) {
+	scale, err := DurationScale(f.DurationUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(fields))
+	for i, name := range fields {
+		sf, ok := selectableFields[name]
+		if !ok {
+			return nil, fmt.Errorf("monitoring: unknown field %q in fields", name)
+		}
+		columns[i] = sf.column
+	}
+
+	res, err := s.paginate(s.filterQuery(ctx, f), f.BaseFilter, columns...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]any, len(res.Data))
+	for i := range res.Data {
+		r := &res.Data[i]
+		r.Duration *= scale
+		row := make(map[string]any, len(fields))
+		for _, name := range fields {
+			row[name] = selectableFields[name].value(r)
+		}
+		rows[i] = row
+	}
+	return &dto.ListResponse[map[string]any]{
+		Total:      res.Total,
+		Data:       rows,
+		Page:       res.Page,
+		PerPage:    res.PerPage,
+		TotalPages: res.TotalPages,
+	}, nil
+}
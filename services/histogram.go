@@ -0,0 +1,34 @@
+package services
+
+// HistogramBin is one duration range of a flat histogram.
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// HistogramAnalyzeResult is AnalyzeResult with Duration flattened into
+// histogram bins, as returned for /requests/analyze?format=histogram.
+type HistogramAnalyzeResult struct {
+	*AnalyzeResult
+	Duration []HistogramBin `json:"duration"`
+}
+
+// Histogram returns one bin per pair of adjacent DurationBoundaries,
+// including empty ones, without the per-request Data. Bins are in the
+// result's duration unit.
+func (r *AnalyzeResult) Histogram() []HistogramBin {
+	b := r.DurationBoundaries
+	if len(b) < 2 {
+		return []HistogramBin{}
+	}
+	counts := make(map[float64]int, len(r.Duration))
+	for _, d := range r.Duration {
+		counts[d.ID] = d.Count
+	}
+	bins := make([]HistogramBin, len(b)-1)
+	for i := range bins {
+		bins[i] = HistogramBin{From: b[i], To: b[i+1], Count: counts[b[i]]}
+	}
+	return bins
+}
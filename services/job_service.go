@@ -1,36 +1,114 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/aghiadodeh/go-monitoring/dto"
 	"github.com/aghiadodeh/go-monitoring/models"
+	"github.com/aghiadodeh/go-monitoring/schema"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // JobService handles job-log CRUD and queries.
 type JobService struct {
 	DB *gorm.DB
+
+	// DefaultLookback is the date range used when a request has no
+	// fromDate (default: 24h).
+	DefaultLookback time.Duration
+
+	// OnClear, when set, is called after logs are cleared or archived,
+	// e.g. to invalidate cached analytics.
+	OnClear func()
+
+	schemasMu sync.RWMutex
+	schemas   map[string]*schema.Schema
+}
+
+// RegisterSchema compiles a JSON Schema and requires metadata of jobs
+// named name to match it. Registering again replaces the schema.
+func (s *JobService) RegisterSchema(name string, doc []byte) error {
+	sc, err := schema.Compile(doc)
+	if err != nil {
+		return fmt.Errorf("monitoring: invalid schema for job %q: %w", name, err)
+	}
+	s.schemasMu.Lock()
+	defer s.schemasMu.Unlock()
+	if s.schemas == nil {
+		s.schemas = make(map[string]*schema.Schema)
+	}
+	s.schemas[name] = sc
+	return nil
+}
+
+// validateMetadata checks metadata against the schema registered for
+// name. Jobs without a schema are not validated.
+func (s *JobService) validateMetadata(name string, metadata datatypes.JSON) error {
+	s.schemasMu.RLock()
+	sc := s.schemas[name]
+	s.schemasMu.RUnlock()
+	if sc == nil {
+		return nil
+	}
+	if err := sc.ValidateJSON(metadata); err != nil {
+		return fmt.Errorf("monitoring: metadata for job %q does not match schema: %w", name, err)
+	}
+	return nil
 }
 
 // Create inserts a new job log record.
 // metadata must be a value that is serializable to valid JSON (struct, map,
 // slice, json.RawMessage, etc.). Channels, funcs and other non-serializable
 // types will return an error immediately without touching the database.
-func (s *JobService) Create(name string, success bool, metadata any) error {
+func (s *JobService) Create(ctx context.Context, name string, success bool, metadata any) error {
 	metaJSON, err := toJSON(metadata)
 	if err != nil {
 		return fmt.Errorf("monitoring: metadata is not valid JSON: %w", err)
 	}
-	return s.DB.Create(&models.JobLog{
+	if err := s.validateMetadata(name, metaJSON); err != nil {
+		return err
+	}
+	return s.DB.WithContext(ctx).Create(&models.JobLog{
 		Name:     name,
 		Success:  success,
 		Metadata: metaJSON,
 	}).Error
 }
 
+// JobLogInput is one job result for CreateBatch.
+type JobLogInput struct {
+	Name     string
+	Success  bool
+	Metadata any // serializable to JSON, as for Create
+}
+
+// CreateBatch validates the metadata of every entry and, only if all are
+// valid, inserts them with a single multi-row INSERT. Nothing is stored
+// when any entry fails validation.
+func (s *JobService) CreateBatch(ctx context.Context, entries []JobLogInput) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	logs := make([]models.JobLog, len(entries))
+	for i, e := range entries {
+		metaJSON, err := toJSON(e.Metadata)
+		if err != nil {
+			return fmt.Errorf("monitoring: metadata of entry %d is not valid JSON: %w", i, err)
+		}
+		if err := s.validateMetadata(e.Name, metaJSON); err != nil {
+			return fmt.Errorf("%w (entry %d)", err, i)
+		}
+		logs[i] = models.JobLog{Name: e.Name, Success: e.Success, Metadata: metaJSON}
+	}
+	return s.DB.WithContext(ctx).Create(&logs).Error
+}
+
 // toJSON converts v to a datatypes.JSON value, validating that the result
 // is well-formed JSON. If v is already json.RawMessage or []byte it is
 // validated in place without a redundant marshal→unmarshal round-trip.
@@ -61,9 +139,9 @@ func toJSON(v any) (datatypes.JSON, error) {
 }
 
 // FindAll returns a paginated, filtered list of job logs.
-func (s *JobService) FindAll(f dto.JobFilter) (*dto.ListResponse[models.JobLog], error) {
-	from, to := parseDateRange(f.BaseFilter)
-	q := s.DB.Model(&models.JobLog{}).Where("created_at BETWEEN ? AND ?", from, to)
+func (s *JobService) FindAll(ctx context.Context, f dto.JobFilter) (*dto.ListResponse[models.JobLog], error) {
+	from, to := parseDateRange(f.BaseFilter, s.DefaultLookback)
+	q := s.DB.WithContext(ctx).Model(&models.JobLog{}).Where("created_at BETWEEN ? AND ?", from, to)
 
 	if f.Name != "" {
 		q = q.Where("name LIKE ?", "%"+f.Name+"%")
@@ -87,20 +165,201 @@ func (s *JobService) FindAll(f dto.JobFilter) (*dto.ListResponse[models.JobLog],
 		return nil, err
 	}
 
-	return &dto.ListResponse[models.JobLog]{Total: total, Data: rows}, nil
+	return dto.NewListResponse(rows, total, skip/perPage+1, perPage), nil
+}
+
+// JobAnalyzeResult is the shape returned by JobService.Analyze.
+type JobAnalyzeResult struct {
+	FromDate time.Time  `json:"fromDate"`
+	ToDate   time.Time  `json:"toDate"`
+	Jobs     []JobStats `json:"jobs"`
+}
+
+// JobStats aggregates executions of a single job name.
+type JobStats struct {
+	Name        string    `json:"name"`
+	Total       int64     `json:"total"`
+	Success     int64     `json:"success"`
+	Failure     int64     `json:"failure"`
+	SuccessRate float64   `json:"successRate"` // 0–100
+	LastRunAt   time.Time `json:"lastRunAt"`
+}
+
+// Analyze returns per-job run statistics for the given date range.
+func (s *JobService) Analyze(ctx context.Context, f dto.BaseFilter) (*JobAnalyzeResult, error) {
+	from, to := parseDateRange(f, s.DefaultLookback)
+
+	var rows []struct {
+		Name      string
+		Total     int64
+		Success   int64
+		LastRunAt time.Time
+	}
+	err := s.DB.WithContext(ctx).Model(&models.JobLog{}).
+		Select("name, COUNT(*) AS total, SUM(CASE WHEN success THEN 1 ELSE 0 END) AS success, MAX(created_at) AS last_run_at").
+		Where("created_at BETWEEN ? AND ?", from, to).
+		Group("name").
+		Order("name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]JobStats, 0, len(rows))
+	for _, r := range rows {
+		var rate float64
+		if r.Total > 0 {
+			rate = float64(r.Success) / float64(r.Total) * 100
+		}
+		jobs = append(jobs, JobStats{
+			Name:        r.Name,
+			Total:       r.Total,
+			Success:     r.Success,
+			Failure:     r.Total - r.Success,
+			SuccessRate: rate,
+			LastRunAt:   r.LastRunAt,
+		})
+	}
+
+	return &JobAnalyzeResult{FromDate: from, ToDate: to, Jobs: jobs}, nil
 }
 
 // FindByID returns a single job log by primary key.
-func (s *JobService) FindByID(id string) (*models.JobLog, error) {
+func (s *JobService) FindByID(ctx context.Context, id string) (*models.JobLog, error) {
 	var j models.JobLog
-	err := s.DB.First(&j, "id = ?", id).Error
+	err := s.DB.WithContext(ctx).First(&j, "id = ?", id).Error
 	return &j, err
 }
 
+// archiveBatchSize is the number of rows copied per INSERT while archiving.
+const archiveBatchSize = 500
+
+// ArchiveBefore moves request and job logs created before t into dest,
+// which must contain tables with the same names and columns (e.g. a
+// separate archive database or schema). Rows are copied in batches and
+// then deleted from the live tables inside a transaction, so a failure
+// leaves the live tables untouched. The copy cannot join that
+// transaction, so rows already in dest (by primary key) are skipped and
+// a failed run can simply be repeated without duplicating the archive.
+func (s *JobService) ArchiveBefore(ctx context.Context, t time.Time, dest *gorm.DB) error {
+	if dest == nil {
+		return fmt.Errorf("monitoring: archive destination is nil")
+	}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := archiveTable[models.RequestLog](tx, dest.WithContext(ctx), t); err != nil {
+			return err
+		}
+		return archiveTable[models.JobLog](tx, dest.WithContext(ctx), t)
+	})
+	if err == nil {
+		s.cleared()
+	}
+	return err
+}
+
+// archiveTable copies rows of T older than t from tx to dest, then
+// deletes them from tx.
+func archiveTable[T any](tx, dest *gorm.DB, t time.Time) error {
+	var batch []T
+	res := tx.Where("created_at < ?", t).FindInBatches(&batch, archiveBatchSize, func(_ *gorm.DB, _ int) error {
+		return dest.Clauses(clause.OnConflict{DoNothing: true}).Create(&batch).Error
+	})
+	if res.Error != nil {
+		return fmt.Errorf("monitoring: archiving %T: %w", *new(T), res.Error)
+	}
+	var zero T
+	return tx.Where("created_at < ?", t).Delete(&zero).Error
+}
+
 // ClearAll deletes all monitoring data (request logs + job logs).
-func (s *JobService) ClearAll() error {
-	if err := s.DB.Where("1 = 1").Delete(&models.RequestLog{}).Error; err != nil {
-		return err
+func (s *JobService) ClearAll(ctx context.Context) error {
+	return s.Clear(ctx, ClearOptions{})
+}
+
+// ClearTables selects which tables Clear operates on.
+type ClearTables string
+
+const (
+	ClearBoth     ClearTables = "both"
+	ClearRequests ClearTables = "requests"
+	ClearJobs     ClearTables = "jobs"
+)
+
+// ClearOptions narrows what Clear deletes. The zero value clears everything.
+type ClearOptions struct {
+	Before time.Time   // only rows created before this time (zero = no limit)
+	Key    string      // only request logs with this key (job logs are unaffected)
+	Tables ClearTables // which tables to clear (default: both)
+}
+
+// Clear deletes monitoring data matching opts.
+func (s *JobService) Clear(ctx context.Context, opts ClearOptions) error {
+	reqQ, jobQ := s.clearQueries(ctx, opts)
+	defer s.cleared()
+	if reqQ != nil {
+		if err := reqQ.Delete(&models.RequestLog{}).Error; err != nil {
+			return err
+		}
+	}
+	if jobQ != nil {
+		return jobQ.Delete(&models.JobLog{}).Error
+	}
+	return nil
+}
+
+// cleared runs the OnClear hook, if any.
+func (s *JobService) cleared() {
+	if s.OnClear != nil {
+		s.OnClear()
+	}
+}
+
+// CountAll returns the total number of request and job logs, i.e. what
+// ClearAll would delete.
+func (s *JobService) CountAll(ctx context.Context) (requests int64, jobs int64, err error) {
+	return s.Count(ctx, ClearOptions{})
+}
+
+// Count returns how many request and job logs Clear(ctx, opts) would delete.
+func (s *JobService) Count(ctx context.Context, opts ClearOptions) (requests int64, jobs int64, err error) {
+	reqQ, jobQ := s.clearQueries(ctx, opts)
+	if reqQ != nil {
+		if err = reqQ.Model(&models.RequestLog{}).Count(&requests).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	if jobQ != nil {
+		if err = jobQ.Model(&models.JobLog{}).Count(&jobs).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	return requests, jobs, nil
+}
+
+// clearQueries builds the conditions for each table selected by opts.
+// A nil query means the table is not affected.
+func (s *JobService) clearQueries(ctx context.Context, opts ClearOptions) (reqQ, jobQ *gorm.DB) {
+	if opts.Tables == "" {
+		opts.Tables = ClearBoth
+	}
+
+	if opts.Tables == ClearBoth || opts.Tables == ClearRequests {
+		reqQ = s.DB.WithContext(ctx).Where("1 = 1")
+		if !opts.Before.IsZero() {
+			reqQ = reqQ.Where("created_at < ?", opts.Before)
+		}
+		if opts.Key != "" {
+			// Map conditions let GORM quote the reserved "key" column per dialect.
+			reqQ = reqQ.Where(map[string]any{"key": opts.Key})
+		}
+	}
+
+	if (opts.Tables == ClearBoth && opts.Key == "") || opts.Tables == ClearJobs {
+		// The audit trail records the clear itself and is never cleared.
+		jobQ = s.DB.WithContext(ctx).Where("name <> ?", AuditJobName)
+		if !opts.Before.IsZero() {
+			jobQ = jobQ.Where("created_at < ?", opts.Before)
+		}
 	}
-	return s.DB.Where("1 = 1").Delete(&models.JobLog{}).Error
+	return reqQ, jobQ
 }
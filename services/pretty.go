@@ -0,0 +1,37 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+)
+
+// PrettyRequestLog is a RequestLog whose Request and Response JSON are
+// re-indented for display, as returned for /requests/view/:id?pretty=true.
+// They are strings because JSON encoders compact nested JSON values;
+// storage stays compact.
+type PrettyRequestLog struct {
+	*models.RequestLog
+	Request  string `json:"request"`
+	Response string `json:"response"`
+}
+
+// Pretty returns r with its request and response JSON indented.
+func Pretty(r *models.RequestLog) PrettyRequestLog {
+	return PrettyRequestLog{
+		RequestLog: r,
+		Request:    indentJSON(r.Request),
+		Response:   indentJSON(r.Response),
+	}
+}
+
+// indentJSON indents b with two spaces, returning it unchanged if it is
+// not valid JSON.
+func indentJSON(b []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Correlation strategies for RelatedRequests.
+const (
+	// RelatedTrace matches requests with the same trace ID.
+	RelatedTrace = "trace"
+	// RelatedUser matches requests of the same user within the window.
+	RelatedUser = "user"
+	// RelatedAuto matches either.
+	RelatedAuto = "auto"
+)
+
+// Defaults for RelatedRequests.
+const (
+	defaultRelatedWindow    = 5 * time.Minute
+	defaultRelatedUserField = "id"
+	maxRelatedRequests      = 200
+)
+
+// Errors returned by RelatedRequests.
+var (
+	ErrInvalidRelatedStrategy = errors.New("monitoring: strategy must be trace, user or auto")
+	ErrRequestNotFound        = errors.New("monitoring: request not found")
+)
+
+// RelatedOptions tunes RelatedRequests. Zero values fall back to the
+// RequestService settings.
+type RelatedOptions struct {
+	Strategy string        // RelatedTrace, RelatedUser or RelatedAuto
+	Window   time.Duration // user matches must be within ± Window of the request
+}
+
+// RelatedRequests returns the requests correlated with the request id,
+// oldest first, to reconstruct the session around it. Depending on the
+// strategy it matches the same trace ID, or the same user (compared on
+// RelatedUserField) within the window. The request itself is excluded;
+// at most 200 rows are returned.
+func (s *RequestService) RelatedRequests(ctx context.Context, id string, opts RelatedOptions) ([]models.RequestLog, error) {
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = s.RelatedStrategy
+	}
+	if strategy == "" {
+		strategy = RelatedAuto
+	}
+	if strategy != RelatedTrace && strategy != RelatedUser && strategy != RelatedAuto {
+		return nil, ErrInvalidRelatedStrategy
+	}
+	window := opts.Window
+	if window <= 0 {
+		window = s.RelatedWindow
+	}
+	if window <= 0 {
+		window = defaultRelatedWindow
+	}
+	userField := s.RelatedUserField
+	if userField == "" {
+		userField = defaultRelatedUserField
+	}
+	keys, err := UserFieldPath(userField)
+	if err != nil {
+		return nil, err
+	}
+
+	origin, err := s.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.DB.WithContext(ctx)
+	var conds []*gorm.DB
+	if strategy != RelatedUser && origin.TraceID != "" {
+		conds = append(conds, db.Where("trace_id = ?", origin.TraceID))
+	}
+	if strategy != RelatedTrace {
+		if value, ok := userValue(origin.User, keys); ok {
+			conds = append(conds, db.Where(datatypes.JSONQuery("user").Equals(value, keys...)).
+				Where("created_at BETWEEN ? AND ?", origin.CreatedAt.Add(-window), origin.CreatedAt.Add(window)))
+		}
+	}
+	if len(conds) == 0 {
+		return []models.RequestLog{}, nil
+	}
+
+	match := conds[0]
+	for _, c := range conds[1:] {
+		match = match.Or(c)
+	}
+	var rows []models.RequestLog
+	err = db.Model(&models.RequestLog{}).
+		Where(match).
+		Where("id <> ?", origin.ID).
+		Order("created_at ASC").
+		Limit(maxRelatedRequests).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].DecodeBodies()
+	}
+	return rows, nil
+}
+
+// userValue returns the scalar at keys inside the stored user JSON.
+// Integral numbers are returned as int64 so they compare correctly on
+// every driver.
+func userValue(user datatypes.JSON, keys []string) (any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(user))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		v = m[k]
+	}
+	switch x := v.(type) {
+	case string:
+		return x, x != ""
+	case bool:
+		return x, true
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n, true
+		}
+		f, err := x.Float64()
+		return f, err == nil
+	default:
+		return nil, false
+	}
+}
@@ -1,38 +1,189 @@
 package services
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aghiadodeh/go-monitoring/dto"
 	"github.com/aghiadodeh/go-monitoring/models"
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 // RequestService handles all request-log queries and analytics.
 type RequestService struct {
 	DB *gorm.DB
+
+	// MethodGroups folds methods together during Analyze aggregation
+	// (e.g. {"HEAD": "GET"}). Stored logs keep their original method.
+	MethodGroups map[string]string
+
+	// ExceptionStatusCodes defines which response status codes count as
+	// exceptions (default: [500]).
+	ExceptionStatusCodes []int
+
+	// MaxConcurrentAnalyze caps how many Analyze calls may run at once
+	// (default: 0 = unlimited). Excess calls fail with ErrAnalyzeBusy.
+	MaxConcurrentAnalyze int
+
+	// DefaultLookback is the date range used when a request has no
+	// fromDate (default: 24h).
+	DefaultLookback time.Duration
+
+	// AnalyzeCacheTTL serves repeated Analyze calls with the same filter
+	// from memory for this long (default: 0 = no caching).
+	AnalyzeCacheTTL time.Duration
+
+	// MaxAnalyzeRange rejects Analyze and export requests whose date
+	// range is longer than this (default: 0 = unlimited).
+	MaxAnalyzeRange time.Duration
+
+	// RelatedStrategy is the default correlation for RelatedRequests:
+	// RelatedTrace, RelatedUser or RelatedAuto (default: auto).
+	// RelatedWindow bounds user matches to ± this long around the request
+	// (default: 5m), and RelatedUserField is the dotted path inside the
+	// stored user JSON that identifies the user (default: "id").
+	RelatedStrategy  string
+	RelatedWindow    time.Duration
+	RelatedUserField string
+
+	// ExcludeFromAnalytics lists path prefixes (e.g. "/health", "/api/*/ping")
+	// left out of Analyze, AnalyzeByEndpoint, Compare, SLOReport and
+	// BucketItems, e.g. noisy health checks. The logs stay in FindAll.
+	ExcludeFromAnalytics []string
+
+	analyzeOnce sync.Once
+	analyzeSem  chan struct{}
+	cache       analyzeCache
+	facets      facetsCache
+}
+
+// ErrAnalyzeBusy is returned by Analyze when MaxConcurrentAnalyze calls
+// are already in flight.
+var ErrAnalyzeBusy = errors.New("monitoring: too many concurrent analyze requests, try again later")
+
+// acquireAnalyze reserves an Analyze slot. The returned release func
+// must be called when the analysis finishes.
+func (s *RequestService) acquireAnalyze() (release func(), err error) {
+	if s.MaxConcurrentAnalyze <= 0 {
+		return func() {}, nil
+	}
+	s.analyzeOnce.Do(func() {
+		s.analyzeSem = make(chan struct{}, s.MaxConcurrentAnalyze)
+	})
+	select {
+	case s.analyzeSem <- struct{}{}:
+		return func() { <-s.analyzeSem }, nil
+	default:
+		return nil, ErrAnalyzeBusy
+	}
 }
 
 // FindAll returns a paginated, filtered list of request logs.
-func (s *RequestService) FindAll(f dto.RequestFilter) (*dto.ListResponse[models.RequestLog], error) {
-	from, to := parseDateRange(f.BaseFilter)
-	q := s.DB.Model(&models.RequestLog{}).Where("created_at BETWEEN ? AND ?", from, to)
+func (s *RequestService) FindAll(ctx context.Context, f dto.RequestFilter) (*dto.ListResponse[models.RequestLog], error) {
+	scale, err := DurationScale(f.DurationUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.paginate(s.filterQuery(ctx, f), f.BaseFilter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.Data {
+		res.Data[i].Duration *= scale
+	}
+	return res, nil
+}
+
+// FindAllRaw returns a paginated list of request logs narrowed only by
+// the given GORM scopes, for filters RequestFilter does not support:
+//
+//	svc.FindAllRaw(ctx, f, func(db *gorm.DB) *gorm.DB {
+//		return db.Where("response->>'statusCode' = ?", "418")
+//	})
+//
+// f supplies page, per_page and sortKey; its date range and other
+// fields are ignored. Scopes are trusted input and must never be built
+// from unescaped user data.
+func (s *RequestService) FindAllRaw(ctx context.Context, f dto.BaseFilter, scopes ...func(*gorm.DB) *gorm.DB) (*dto.ListResponse[models.RequestLog], error) {
+	q := s.DB.WithContext(ctx).Model(&models.RequestLog{}).Scopes(scopes...)
+	return s.paginate(q, f)
+}
+
+// paginate counts the rows matched by q and loads the requested page,
+// newest first unless f.SortKey says otherwise. When columns are given,
+// only they are loaded; the count is unaffected, since COUNT over a
+// multi-column SELECT is invalid SQL.
+func (s *RequestService) paginate(q *gorm.DB, f dto.BaseFilter, columns ...string) (*dto.ListResponse[models.RequestLog], error) {
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	perPage, skip := pagination(f)
+	sortKey := f.SortKey
+	if sortKey == "" {
+		sortKey = "created_at"
+	}
+
+	page := q.Session(&gorm.Session{})
+	if len(columns) > 0 {
+		page = page.Select(columns)
+	}
+	var rows []models.RequestLog
+	err := page.Order(sortKey + " DESC").Offset(skip).Limit(perPage).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].DecodeBodies()
+	}
+
+	return dto.NewListResponse(rows, total, skip/perPage+1, perPage), nil
+}
+
+// filterQuery builds the WHERE clause shared by FindAll and ExportNDJSON.
+func (s *RequestService) filterQuery(ctx context.Context, f dto.RequestFilter) *gorm.DB {
+	from, to := parseDateRange(f.BaseFilter, s.DefaultLookback)
+	q := s.DB.WithContext(ctx).Model(&models.RequestLog{}).Scopes(DateRangeScope(from, to))
 
 	if f.Exception != nil && *f.Exception {
-		q = q.Where("response->>'statusCode' = '500'")
+		q = q.Where("response->>'statusCode' IN ?", s.exceptionCodes())
 	} else if f.StatusCode != nil {
-		q = q.Where("response->>'statusCode' = ?", strconv.Itoa(*f.StatusCode))
+		q = q.Scopes(StatusScope(*f.StatusCode))
+	}
+	if f.HasException != nil {
+		if *f.HasException {
+			q = q.Where("response->>'exception' IS NOT NULL")
+		} else {
+			q = q.Where("response->>'exception' IS NULL")
+		}
+	}
+	if f.StatusCodes != "" {
+		codes, err := ParseStatusCodes(f.StatusCodes)
+		if err != nil {
+			_ = q.AddError(err)
+			return q
+		}
+		q = q.Scopes(StatusCodesScope(codes...))
 	}
 	if f.URL != "" {
 		q = q.Where("url LIKE ?", "%"+f.URL+"%")
 	}
 	if f.Method != "" {
-		methods := strings.Split(f.Method, ",")
-		q = q.Where("method IN ?", methods)
+		q = q.Where("method IN ?", splitMethods(f.Method))
 	}
 	if f.Success != nil {
 		q = q.Where("success = ?", *f.Success)
@@ -43,30 +194,174 @@ func (s *RequestService) FindAll(f dto.RequestFilter) (*dto.ListResponse[models.
 	if f.DurationLt != nil {
 		q = q.Where("duration <= ?", *f.DurationLt)
 	}
+	if f.TraceID != "" {
+		q = q.Where("trace_id = ?", f.TraceID)
+	}
+	if f.Key != "" {
+		// Map conditions let GORM quote the reserved "key" column per dialect.
+		q = q.Where(map[string]any{"key": f.Key})
+	}
+	for _, pair := range strings.Split(f.Param, ",") {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || key == "" {
+			continue
+		}
+		q = q.Where(datatypes.JSONQuery("route_params").Equals(value, key))
+	}
+	for _, pair := range strings.Split(f.Field, ",") {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || key == "" {
+			continue
+		}
+		q = q.Where(datatypes.JSONQuery("indexed_fields").Equals(value, key))
+	}
+	for _, tag := range strings.Split(f.Tag, ",") {
+		name, value, hasValue := strings.Cut(tag, ":")
+		if name == "" {
+			continue
+		}
+		if hasValue {
+			q = q.Where(datatypes.JSONQuery("tags").Equals(value, name))
+		} else {
+			q = q.Where(datatypes.JSONQuery("tags").HasKey(name))
+		}
+	}
+	if f.UserField != "" {
+		keys, err := UserFieldPath(f.UserField)
+		if err != nil {
+			_ = q.AddError(err)
+			return q
+		}
+		q = q.Where(datatypes.JSONQuery("user").Equals(f.UserValue, keys...))
+	}
+	return q
+}
+
+// ParseStatusCodes parses a comma-separated list of status codes such as
+// "400,401,403".
+func ParseStatusCodes(list string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("monitoring: invalid status code %q", part)
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("monitoring: statusCodes is empty")
+	}
+	return codes, nil
+}
 
-	var total int64
-	q.Count(&total)
+// userFieldSegmentRe restricts user JSON path segments to safe identifiers.
+var userFieldSegmentRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
 
-	perPage, skip := pagination(f.BaseFilter)
+// UserFieldPath splits a dotted user JSON path (e.g. "org.role") into its
+// keys, rejecting anything that is not a plain identifier.
+func UserFieldPath(field string) ([]string, error) {
+	keys := strings.Split(field, ".")
+	for _, k := range keys {
+		if !userFieldSegmentRe.MatchString(k) {
+			return nil, fmt.Errorf("monitoring: invalid userField %q", field)
+		}
+	}
+	return keys, nil
+}
+
+// ExportNDJSON streams every request log matching f to w as
+// newline-delimited JSON, one record per line. Unlike FindAll the result
+// is not paginated; rows are read through a cursor and w is flushed every
+// ndjsonFlushEvery records to keep memory bounded.
+func (s *RequestService) ExportNDJSON(ctx context.Context, f dto.RequestFilter, w *bufio.Writer) error {
 	sortKey := f.SortKey
 	if sortKey == "" {
 		sortKey = "created_at"
 	}
 
+	rows, err := s.filterQuery(ctx, f).Order(sortKey + " DESC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	n := 0
+	for rows.Next() {
+		var r models.RequestLog
+		if err := s.DB.ScanRows(rows, &r); err != nil {
+			return err
+		}
+		r.DecodeBodies()
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		n++
+		if n%ndjsonFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// ndjsonFlushEvery is the number of records written between flushes.
+const ndjsonFlushEvery = 100
+
+// DurationBoundaries are the duration bucket edges (in ms) used by
+// Analyze; each bucket covers [DurationBoundaries[i], DurationBoundaries[i+1]).
+var DurationBoundaries = []float64{0, 20, 40, 80, 130, 150, 180, 200, 500, 1000, 2000}
+
+// defaultMaxBucketItems is the number of sample requests Analyze embeds
+// per bucket when AnalyzeOptions.MaxBucketItems is unset.
+const defaultMaxBucketItems = 50
+
+// maxSlowestLimit caps the number of rows SlowestRequests returns.
+const maxSlowestLimit = 100
+
+// SlowestRequests returns the limit slowest requests in the date range,
+// ordered by duration descending. limit defaults to 10 and is capped at
+// 100.
+func (s *RequestService) SlowestRequests(ctx context.Context, f dto.BaseFilter, limit int) ([]models.RequestLog, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > maxSlowestLimit {
+		limit = maxSlowestLimit
+	}
+
+	from, to := parseDateRange(f, s.DefaultLookback)
 	var rows []models.RequestLog
-	err := q.Order(sortKey + " DESC").Offset(skip).Limit(perPage).Find(&rows).Error
+	err := s.DB.WithContext(ctx).Model(&models.RequestLog{}).
+		Scopes(DateRangeScope(from, to)).
+		Order("duration DESC").
+		Limit(limit).
+		Find(&rows).Error
 	if err != nil {
 		return nil, err
 	}
-
-	return &dto.ListResponse[models.RequestLog]{Total: total, Data: rows}, nil
+	for i := range rows {
+		rows[i].DecodeBodies()
+	}
+	return rows, nil
 }
 
 // FindByID returns a single request log.
-func (s *RequestService) FindByID(id string) (*models.RequestLog, error) {
+func (s *RequestService) FindByID(ctx context.Context, id string) (*models.RequestLog, error) {
 	var r models.RequestLog
-	err := s.DB.First(&r, "id = ?", id).Error
-	return &r, err
+	if err := s.DB.WithContext(ctx).First(&r, "id = ?", id).Error; err != nil {
+		return &r, err
+	}
+	r.DecodeBodies()
+	return &r, nil
 }
 
 // AnalyzeResult is the shape returned by Analyze.
@@ -76,10 +371,23 @@ type AnalyzeResult struct {
 	Total              int64            `json:"total"`
 	Success            int64            `json:"success"`
 	Exceptions         int64            `json:"exceptions"`
+	HandlerErrors      int64            `json:"handlerErrors"` // requests whose handler returned a Go error
 	Duration           []DurationBucket `json:"duration"`
 	DurationURLs       []DurationURL    `json:"durationURLs"`
 	CreatedAt          []TimeBucket     `json:"createdAt"`
 	DurationBoundaries []float64        `json:"durationBoundaries"`
+	Methods            []MethodCount    `json:"methods"`
+	Cache              string           `json:"cache"` // "hit" or "miss"
+
+	// StatusSeries splits the CreatedAt buckets by status class.
+	StatusSeries []StatusTimeBucket `json:"statusSeries"`
+}
+
+// MethodCount is the number of requests per HTTP method.
+type MethodCount struct {
+	Method       string `json:"method"`
+	Count        int    `json:"count"`
+	SuccessCount int    `json:"successCount"`
 }
 
 // DurationBucket groups requests by response-time range.
@@ -109,9 +417,49 @@ type DurationURL struct {
 
 // TimeBucket groups requests into time-series intervals.
 type TimeBucket struct {
-	ID    time.Time        `json:"id"`
-	Count int              `json:"count"`
-	Data  []TimeBucketItem `json:"data"`
+	ID           time.Time        `json:"id"`
+	Count        int              `json:"count"`
+	SuccessCount int              `json:"successCount"`
+	ErrorCount   int              `json:"errorCount"`
+	Data         []TimeBucketItem `json:"data"`
+}
+
+// StatusTimeBucket counts the requests of a time bucket per status
+// class, for stacked charts. ID matches the TimeBucket's; Other holds
+// 1xx and requests without a status code.
+type StatusTimeBucket struct {
+	ID        time.Time `json:"id"`
+	Status2xx int       `json:"2xx"`
+	Status3xx int       `json:"3xx"`
+	Status4xx int       `json:"4xx"`
+	Status5xx int       `json:"5xx"`
+	Other     int       `json:"other"`
+}
+
+// add counts one request with the given status code.
+func (b *StatusTimeBucket) add(code int) {
+	switch code / 100 {
+	case 2:
+		b.Status2xx++
+	case 3:
+		b.Status3xx++
+	case 4:
+		b.Status4xx++
+	case 5:
+		b.Status5xx++
+	default:
+		b.Other++
+	}
+}
+
+// responseStatusCode extracts statusCode from a stored response column,
+// returning 0 when it is missing.
+func responseStatusCode(response []byte) int {
+	var v struct {
+		StatusCode int `json:"statusCode"`
+	}
+	_ = json.Unmarshal(response, &v)
+	return v.StatusCode
 }
 
 // TimeBucketItem is a single request inside a time bucket.
@@ -123,53 +471,75 @@ type TimeBucketItem struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
-// Analyze returns aggregate analytics for the given date range.
-func (s *RequestService) Analyze(f dto.BaseFilter) (*AnalyzeResult, error) {
-	from, to := parseDateRange(f)
+// Analyze returns aggregate analytics for the given date range. When
+// AnalyzeCacheTTL is set, fresh results for the same filter are served
+// from memory.
+func (s *RequestService) Analyze(ctx context.Context, f dto.AnalyzeOptions) (*AnalyzeResult, error) {
+	scale, err := DurationScale(f.DurationUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.AnalyzeCacheTTL <= 0 {
+		return s.analyze(ctx, f, scale)
+	}
+	key := analyzeCacheKey(f)
+	if r, ok := s.cache.get(key); ok {
+		r.Cache = CacheHit
+		return r, nil
+	}
+	r, err := s.analyze(ctx, f, scale)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.set(key, r, s.AnalyzeCacheTTL)
+	return r, nil
+}
+
+// analyze runs the aggregation queries behind Analyze.
+func (s *RequestService) analyze(ctx context.Context, f dto.AnalyzeOptions, scale float64) (*AnalyzeResult, error) {
+
+	release, err := s.acquireAnalyze()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	from, to := parseDateRange(f.BaseFilter, s.DefaultLookback)
 
-	baseWhere := "created_at BETWEEN ? AND ?"
+	base := s.analyzeQuery(ctx, f, from, to)
 
 	var total int64
-	s.DB.Model(&models.RequestLog{}).Where(baseWhere, from, to).Count(&total)
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
 
 	var success int64
-	s.DB.Model(&models.RequestLog{}).Where(baseWhere+" AND success = ?", from, to, true).Count(&success)
+	if err := base.Session(&gorm.Session{}).Where("success = ?", true).Count(&success).Error; err != nil {
+		return nil, err
+	}
 
 	var exceptions int64
-	s.DB.Model(&models.RequestLog{}).Where(baseWhere+" AND response->>'statusCode' = '500'", from, to).Count(&exceptions)
+	if err := base.Session(&gorm.Session{}).Where("response->>'statusCode' IN ?", s.exceptionCodes()).Count(&exceptions).Error; err != nil {
+		return nil, err
+	}
+
+	// Handler errors (panics, GORM errors, fiber.NewError) are stored in
+	// response.exception, independent of the status code that was sent.
+	var handlerErrors int64
+	if err := base.Session(&gorm.Session{}).Where("response->>'exception' IS NOT NULL").Count(&handlerErrors).Error; err != nil {
+		return nil, err
+	}
 
 	// Load all matching requests for in-memory bucketing.
 	var requests []models.RequestLog
-	s.DB.Where(baseWhere, from, to).Find(&requests)
+	if err := base.Session(&gorm.Session{}).Find(&requests).Error; err != nil {
+		return nil, err
+	}
 
 	// ---- duration buckets ----
-	boundaries := []float64{0, 20, 40, 80, 130, 150, 180, 200, 500, 1000, 2000}
-	var durationBuckets []DurationBucket
-	for i := 0; i < len(boundaries)-1; i++ {
-		lo, hi := boundaries[i], boundaries[i+1]
-		var items []DurationBucketItem
-		for _, r := range requests {
-			if r.Duration >= lo && r.Duration < hi {
-				url := r.Path
-				if url == "" {
-					url = r.URL
-				}
-				items = append(items, DurationBucketItem{
-					Duration: r.Duration,
-					URL:      url,
-					Method:   r.Method,
-					Success:  r.Success,
-				})
-			}
-		}
-		if len(items) > 0 {
-			durationBuckets = append(durationBuckets, DurationBucket{
-				ID:    lo,
-				Count: len(items),
-				Data:  items,
-			})
-		}
-	}
+	boundaries := DurationBoundaries
+	durationBuckets := bucketDurations(requests, boundaries)
 
 	// ---- per-endpoint duration stats ----
 	type endpointKey struct{ url, method string }
@@ -177,7 +547,7 @@ func (s *RequestService) Analyze(f dto.BaseFilter) (*AnalyzeResult, error) {
 	for _, b := range durationBuckets {
 		for _, item := range b.Data {
 			cleanURL := strings.SplitN(item.URL, "?", 2)[0]
-			k := endpointKey{url: cleanURL, method: item.Method}
+			k := endpointKey{url: cleanURL, method: s.groupMethod(item.Method)}
 			if item.Success {
 				epMap[k] = append(epMap[k], item.Duration)
 			}
@@ -204,17 +574,44 @@ func (s *RequestService) Analyze(f dto.BaseFilter) (*AnalyzeResult, error) {
 		})
 	}
 
+	maxItems := f.MaxBucketItems
+	if maxItems <= 0 {
+		maxItems = defaultMaxBucketItems
+	}
+	// Per-endpoint stats above need every item; trim samples only now.
+	for i := range durationBuckets {
+		if len(durationBuckets[i].Data) > maxItems {
+			durationBuckets[i].Data = durationBuckets[i].Data[:maxItems]
+		}
+	}
+
 	// ---- time-series buckets ----
-	ranges := buildTimeRange(from, to)
+	ranges := buildTimeRange(from, to, bucketLocation(f.TZ), f.Granularity)
 	if len(ranges) > 0 {
 		ranges = append(ranges, to)
 	}
+	// Status codes are decoded once, not per bucket.
+	statusCodes := make([]int, len(requests))
+	for i, r := range requests {
+		statusCodes[i] = responseStatusCode(r.Response)
+	}
 	var timeBuckets []TimeBucket
+	var statusSeries []StatusTimeBucket
 	for i := 0; i < len(ranges)-1; i++ {
 		start, end := ranges[i], ranges[i+1]
 		var items []TimeBucketItem
-		for _, r := range requests {
+		count, successCount := 0, 0
+		status := StatusTimeBucket{ID: start}
+		for j, r := range requests {
 			if r.CreatedAt.After(start) && r.CreatedAt.Before(end) {
+				count++
+				if r.Success {
+					successCount++
+				}
+				status.add(statusCodes[j])
+				if len(items) >= maxItems {
+					continue
+				}
 				items = append(items, TimeBucketItem{
 					ID:        r.ID,
 					URL:       r.URL,
@@ -224,48 +621,244 @@ func (s *RequestService) Analyze(f dto.BaseFilter) (*AnalyzeResult, error) {
 				})
 			}
 		}
-		if len(items) > 0 {
+		if count > 0 {
 			timeBuckets = append(timeBuckets, TimeBucket{
-				ID:    start,
-				Count: len(items),
-				Data:  items,
+				ID:           start,
+				Count:        count,
+				SuccessCount: successCount,
+				ErrorCount:   count - successCount,
+				Data:         items,
 			})
+			statusSeries = append(statusSeries, status)
 		}
 	}
 
-	return &AnalyzeResult{
+	// ---- per-method counts ----
+	methods := s.methodCounts(requests)
+
+	result := &AnalyzeResult{
 		FromDate:           from,
 		ToDate:             to,
 		Total:              total,
 		Success:            success,
 		Exceptions:         exceptions,
+		HandlerErrors:      handlerErrors,
 		Duration:           durationBuckets,
 		DurationURLs:       durationURLs,
 		CreatedAt:          timeBuckets,
 		DurationBoundaries: boundaries,
-	}, nil
+		Methods:            methods,
+		Cache:              CacheMiss,
+		StatusSeries:       statusSeries,
+	}
+	result.scaleDurations(scale)
+	return result, nil
+}
+
+// methodCounts counts requests per method, folded through MethodGroups
+// like the rest of the Analyze series, most frequent first.
+func (s *RequestService) methodCounts(requests []models.RequestLog) []MethodCount {
+	methodIdx := make(map[string]int)
+	var methods []MethodCount
+	for _, r := range requests {
+		method := s.groupMethod(r.Method)
+		i, ok := methodIdx[method]
+		if !ok {
+			i = len(methods)
+			methodIdx[method] = i
+			methods = append(methods, MethodCount{Method: method})
+		}
+		methods[i].Count++
+		if r.Success {
+			methods[i].SuccessCount++
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Count > methods[j].Count })
+	return methods
+}
+
+// bucketDurations groups requests into the half-open ranges
+// [boundaries[i], boundaries[i+1]) in a single pass, locating each
+// request's bucket by binary search. Empty buckets are omitted and
+// requests outside the boundaries are ignored.
+func bucketDurations(requests []models.RequestLog, boundaries []float64) []DurationBucket {
+	if len(boundaries) < 2 {
+		return nil
+	}
+	items := make([][]DurationBucketItem, len(boundaries)-1)
+	for _, r := range requests {
+		// Index of the last boundary <= r.Duration.
+		i := sort.Search(len(boundaries), func(j int) bool { return boundaries[j] > r.Duration }) - 1
+		if i < 0 || i >= len(items) {
+			continue
+		}
+		url := r.Path
+		if url == "" {
+			url = r.URL
+		}
+		items[i] = append(items[i], DurationBucketItem{
+			Duration: r.Duration,
+			URL:      url,
+			Method:   r.Method,
+			Success:  r.Success,
+		})
+	}
+
+	var buckets []DurationBucket
+	for i, data := range items {
+		if len(data) > 0 {
+			buckets = append(buckets, DurationBucket{
+				ID:    boundaries[i],
+				Count: len(data),
+				Data:  data,
+			})
+		}
+	}
+	return buckets
+}
+
+// analyzeQuery builds the base query for Analyze from the date range and
+// the optional method/URL/success filters, leaving out
+// ExcludeFromAnalytics paths.
+func (s *RequestService) analyzeQuery(ctx context.Context, f dto.AnalyzeOptions, from, to time.Time) *gorm.DB {
+	q := s.DB.WithContext(ctx).Model(&models.RequestLog{}).Scopes(DateRangeScope(from, to), s.excludeFromAnalytics)
+	if f.Method != "" {
+		q = q.Where("method IN ?", splitMethods(f.Method))
+	}
+	if f.URL != "" {
+		q = q.Where("url LIKE ?", "%"+f.URL+"%")
+	}
+	if f.SuccessOnly {
+		q = q.Where("success = ?", true)
+	}
+	return q
+}
+
+// excludeFromAnalytics filters out rows whose path matches an
+// ExcludeFromAnalytics pattern: a prefix, where * matches any run of
+// characters.
+func (s *RequestService) excludeFromAnalytics(db *gorm.DB) *gorm.DB {
+	for _, p := range s.ExcludeFromAnalytics {
+		if p = strings.TrimSpace(p); p != "" {
+			db = db.Where("path NOT LIKE ? ESCAPE '!'", prefixPattern(p))
+		}
+	}
+	return db
+}
+
+// likeEscaper escapes LIKE wildcards with "!", which unlike a backslash
+// needs no quoting in any dialect's string literals.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// prefixPattern turns a path prefix, where * matches any run of
+// characters, into a LIKE pattern for ESCAPE '!'. Other characters,
+// including % and _, match literally.
+func prefixPattern(p string) string {
+	return strings.ReplaceAll(likeEscaper.Replace(p), "*", "%") + "%"
+}
+
+// splitMethods parses a comma-separated method filter. Methods are
+// upper-cased to match how the middleware stores them.
+func splitMethods(s string) []string {
+	methods := strings.Split(s, ",")
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(strings.TrimSpace(m))
+	}
+	return methods
+}
+
+// groupMethod maps method through MethodGroups, returning it unchanged
+// when no mapping exists.
+func (s *RequestService) groupMethod(method string) string {
+	if g, ok := s.MethodGroups[strings.ToUpper(method)]; ok {
+		return g
+	}
+	return method
+}
+
+// exceptionCodes returns the exception status codes as strings, matching
+// the text extracted from the response JSON column.
+func (s *RequestService) exceptionCodes() []string {
+	codes := s.ExceptionStatusCodes
+	if len(codes) == 0 {
+		codes = []int{500}
+	}
+	out := make([]string, len(codes))
+	for i, c := range codes {
+		out[i] = strconv.Itoa(c)
+	}
+	return out
 }
 
 // --- shared helpers ---
 
-func parseDateRange(f dto.BaseFilter) (time.Time, time.Time) {
-	now := time.Now()
-	from := now.Add(-24 * time.Hour)
+// defaultLookback is the date range used when no fromDate is given and
+// DefaultLookback is unset.
+const defaultLookback = 24 * time.Hour
+
+// alignTime truncates t to the start of its granularity period in t's
+// location. Weeks start on Monday.
+func alignTime(t time.Time, granularity string) time.Time {
+	y, m, d := t.Date()
+	switch granularity {
+	case GranularityMinute:
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, t.Location())
+	case GranularityHour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// parseDateRange returns the filter's date range in UTC, so bucket
+// boundaries do not shift with the server's zone or across DST changes
+// and offsets in the query (e.g. +02:00) compare consistently.
+//
+// Without fromDate the range starts lookback before now; a non-positive
+// lookback means defaultLookback.
+func parseDateRange(f dto.BaseFilter, lookback time.Duration) (time.Time, time.Time) {
+	if lookback <= 0 {
+		lookback = defaultLookback
+	}
+	now := time.Now().UTC()
+	from := now.Add(-lookback)
 	to := now
 
 	if f.FromDate != "" {
 		if t, err := time.Parse(time.RFC3339, f.FromDate); err == nil {
-			from = t
+			from = t.UTC()
 		}
 	}
 	if f.ToDate != "" {
 		if t, err := time.Parse(time.RFC3339, f.ToDate); err == nil {
-			to = t
+			to = t.UTC()
 		}
 	}
 	return from, to
 }
 
+// CheckRange reports an error when the filter's date range is longer
+// than MaxAnalyzeRange. Handlers call it before Analyze and exports so an
+// oversized range fails with 400 instead of loading it into memory.
+func (s *RequestService) CheckRange(f dto.BaseFilter) error {
+	if s.MaxAnalyzeRange <= 0 {
+		return nil
+	}
+	from, to := parseDateRange(f, s.DefaultLookback)
+	if to.Sub(from) > s.MaxAnalyzeRange {
+		return fmt.Errorf("monitoring: date range must be at most %s, got %s", s.MaxAnalyzeRange, to.Sub(from).Round(time.Second))
+	}
+	return nil
+}
+
+// maxPerPage caps the page size of paginated listings.
+const maxPerPage = 50
+
 func pagination(f dto.BaseFilter) (perPage int, skip int) {
 	perPage = 20
 	page := 1
@@ -274,8 +867,8 @@ func pagination(f dto.BaseFilter) (perPage int, skip int) {
 			perPage = v
 		}
 	}
-	if perPage > 50 {
-		perPage = 50
+	if perPage > maxPerPage {
+		perPage = maxPerPage
 	}
 	if f.Page != "" {
 		if v, err := strconv.Atoi(f.Page); err == nil && v > 0 {
@@ -286,23 +879,126 @@ func pagination(f dto.BaseFilter) (perPage int, skip int) {
 	return
 }
 
-// buildTimeRange creates evenly spaced time boundaries between from and to.
-func buildTimeRange(from, to time.Time) []time.Time {
-	diff := to.Sub(from)
-	var step time.Duration
+// ValidatePagination reports an error when page or per_page is present
+// but not a positive integer, or per_page exceeds the maximum. FindAll
+// itself is lenient and falls back to defaults; handlers call this in
+// strict mode.
+func ValidatePagination(f dto.BaseFilter) error {
+	if f.Page != "" {
+		if v, err := strconv.Atoi(f.Page); err != nil || v < 1 {
+			return fmt.Errorf("monitoring: page must be a positive integer, got %q", f.Page)
+		}
+	}
+	if f.PerPage != "" {
+		v, err := strconv.Atoi(f.PerPage)
+		if err != nil || v < 1 {
+			return fmt.Errorf("monitoring: per_page must be a positive integer, got %q", f.PerPage)
+		}
+		if v > maxPerPage {
+			return fmt.Errorf("monitoring: per_page must be at most %d, got %d", maxPerPage, v)
+		}
+	}
+	return nil
+}
+
+// bucketLocation resolves the tz query parameter (an IANA zone name such
+// as "Europe/Berlin"). It returns nil when tz is empty and UTC when the
+// zone is unknown.
+func bucketLocation(tz string) *time.Location {
+	if tz == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Time bucket granularities accepted by AnalyzeOptions.Granularity.
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+	GranularityWeek   = "week"
+	GranularityMonth  = "month"
+)
+
+// granularitySteps holds the nominal step of each granularity. Day, week
+// and month steps follow the calendar when buckets are aligned to a zone.
+var granularitySteps = map[string]time.Duration{
+	GranularityMinute: time.Minute,
+	GranularityHour:   time.Hour,
+	GranularityDay:    24 * time.Hour,
+	GranularityWeek:   7 * 24 * time.Hour,
+	GranularityMonth:  30 * 24 * time.Hour, // ~month
+}
+
+// maxTimeBuckets caps the time-series buckets a granularity override may
+// produce, e.g. minute buckets over a day fit but over a week do not.
+const maxTimeBuckets = 1500
+
+// autoGranularity picks the bucket granularity for a range of length diff.
+func autoGranularity(diff time.Duration) string {
 	switch {
 	case diff <= time.Hour:
-		step = time.Minute
+		return GranularityMinute
 	case diff <= 24*time.Hour:
-		step = time.Hour
+		return GranularityHour
 	case diff <= 31*24*time.Hour:
-		step = 24 * time.Hour
+		return GranularityDay
 	default:
-		step = 30 * 24 * time.Hour // ~month
+		return GranularityMonth
+	}
+}
+
+// CheckGranularity validates the granularity query parameter and reports
+// an error when it would split the date range into more than
+// maxTimeBuckets buckets.
+func (s *RequestService) CheckGranularity(f dto.AnalyzeOptions) error {
+	if f.Granularity == "" {
+		return nil
+	}
+	step, ok := granularitySteps[f.Granularity]
+	if !ok {
+		return errors.New("monitoring: granularity must be minute, hour, day, week or month")
+	}
+	from, to := parseDateRange(f.BaseFilter, s.DefaultLookback)
+	if n := int(to.Sub(from) / step); n > maxTimeBuckets {
+		return fmt.Errorf("monitoring: granularity %s yields %d buckets for this range, at most %d allowed", f.Granularity, n, maxTimeBuckets)
+	}
+	return nil
+}
+
+// buildTimeRange creates time bucket boundaries between from and to. The
+// step comes from granularity, or from the range length when it is empty.
+// Without loc the boundaries start at from and are evenly spaced. With
+// loc they align to the start of the minute, hour, day, week (Monday) or
+// month in that zone (so daily buckets begin at local midnight, even
+// across DST changes) and are returned in loc; the first boundary may
+// precede from.
+func buildTimeRange(from, to time.Time, loc *time.Location, granularity string) []time.Time {
+	if _, ok := granularitySteps[granularity]; !ok {
+		granularity = autoGranularity(to.Sub(from))
+	}
+	step := granularitySteps[granularity]
+
+	start := from
+	next := func(t time.Time) time.Time { return t.Add(step) }
+	if loc != nil {
+		start = alignTime(from.In(loc), granularity)
+		switch granularity {
+		case GranularityDay:
+			next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+		case GranularityWeek:
+			next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+		case GranularityMonth:
+			next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+		}
 	}
 
 	var r []time.Time
-	for t := from; t.Before(to); t = t.Add(step) {
+	for t := start; t.Before(to); t = next(t) {
 		r = append(r, t)
 	}
 	if len(r) == 0 {
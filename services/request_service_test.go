@@ -0,0 +1,50 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aghiadodeh/go-monitoring/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPrefixPattern(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/health", "/health%"},
+		{"/api/*/internal", "/api/%/internal%"},
+		{"/api/v1_internal", "/api/v1!_internal%"},
+		{"/100%", "/100!%%"},
+		{"/wow!", "/wow!!%"},
+	}
+	for _, tt := range tests {
+		if got := prefixPattern(tt.in); got != tt.want {
+			t.Errorf("prefixPattern(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExcludeFromAnalyticsSQL(t *testing.T) {
+	db, err := gorm.Open(testDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &RequestService{DB: db, ExcludeFromAnalytics: []string{"/health", " ", "/api/*/v1_x"}}
+
+	var rows []models.RequestLog
+	stmt := db.Model(&models.RequestLog{}).Scopes(s.excludeFromAnalytics).Find(&rows).Statement
+
+	sql := stmt.SQL.String()
+	if n := strings.Count(sql, "path NOT LIKE ? ESCAPE '!'"); n != 2 {
+		t.Errorf("SQL has %d exclusions, want 2: %s", n, sql)
+	}
+	want := []any{"/health%", "/api/%/v1!_x%"}
+	if len(stmt.Vars) != len(want) {
+		t.Fatalf("vars = %v, want %v", stmt.Vars, want)
+	}
+	for i := range want {
+		if stmt.Vars[i] != want[i] {
+			t.Errorf("vars[%d] = %v, want %v", i, stmt.Vars[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reusable GORM scopes for composing custom queries against the
+// monitoring models, e.g.:
+//
+//	db.Model(&models.RequestLog{}).
+//		Scopes(services.DateRangeScope(from, to), services.StatusScope(502), services.PaginationScope(1, 20)).
+//		Find(&logs)
+
+// DateRangeScope restricts rows to created_at BETWEEN from AND to.
+func DateRangeScope(from, to time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("created_at BETWEEN ? AND ?", from, to)
+	}
+}
+
+// StatusScope restricts request logs to a single response status code.
+func StatusScope(code int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("response->>'statusCode' = ?", strconv.Itoa(code))
+	}
+}
+
+// StatusCodesScope restricts request logs to any of the given response
+// status codes.
+func StatusCodesScope(codes ...int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		values := make([]string, len(codes))
+		for i, c := range codes {
+			values[i] = strconv.Itoa(c)
+		}
+		return db.Where("response->>'statusCode' IN ?", values)
+	}
+}
+
+// PaginationScope applies OFFSET/LIMIT for a 1-based page. It uses the
+// same defaults and cap as the API (20 per page, at most 50).
+func PaginationScope(page, perPage int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if page < 1 {
+			page = 1
+		}
+		if perPage <= 0 {
+			perPage = 20
+		}
+		if perPage > 50 {
+			perPage = 50
+		}
+		return db.Offset((page - 1) * perPage).Limit(perPage)
+	}
+}
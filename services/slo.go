@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aghiadodeh/go-monitoring/dto"
+)
+
+// SLORow is the latency SLO attainment of a single endpoint.
+type SLORow struct {
+	Method        string  `json:"method"`
+	Path          string  `json:"path"`
+	Count         int64   `json:"count"`
+	OverThreshold int64   `json:"overThreshold"` // requests slower than the threshold
+	Attainment    float64 `json:"attainment"`    // fraction (0–1) within the threshold
+}
+
+// SLOReport returns, per endpoint, the fraction of requests that completed
+// within thresholdMs and how many did not. Rows are ordered by
+// attainment, worst first, then by request count.
+func (s *RequestService) SLOReport(ctx context.Context, f dto.BaseFilter, thresholdMs float64) ([]SLORow, error) {
+	from, to := parseDateRange(f, s.DefaultLookback)
+
+	var rows []struct {
+		Path          string
+		Method        string
+		Total         int64
+		OverThreshold int64
+	}
+	err := s.analyzeQuery(ctx, dto.AnalyzeOptions{BaseFilter: f}, from, to).
+		Select("path, method, COUNT(*) AS total, "+
+			"SUM(CASE WHEN duration > ? THEN 1 ELSE 0 END) AS over_threshold", thresholdMs).
+		Group("path, method").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Fold grouped methods (e.g. HEAD → GET) into a single entry.
+	type key struct{ path, method string }
+	merged := make(map[key]*SLORow)
+	for _, r := range rows {
+		k := key{path: r.Path, method: s.groupMethod(r.Method)}
+		row, ok := merged[k]
+		if !ok {
+			row = &SLORow{Method: k.method, Path: k.path}
+			merged[k] = row
+		}
+		row.Count += r.Total
+		row.OverThreshold += r.OverThreshold
+	}
+
+	report := make([]SLORow, 0, len(merged))
+	for _, row := range merged {
+		row.Attainment = float64(row.Count-row.OverThreshold) / float64(row.Count)
+		report = append(report, *row)
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Attainment != report[j].Attainment {
+			return report[i].Attainment < report[j].Attainment
+		}
+		return report[i].Count > report[j].Count
+	})
+	return report, nil
+}
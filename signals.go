@@ -0,0 +1,45 @@
+package monitoring
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// shutdownOnSignal shuts app down gracefully when the process receives
+// SIGINT or SIGTERM: in-flight requests finish within timeout, then the
+// logs are flushed, including those of the drained requests. app.Listen
+// returns as soon as the listeners close, so the flush runs here and
+// m.Wait blocks until it is done. The signal is not re-raised. A second
+// signal gets the default behaviour, so it still kills a process stuck
+// in shutdown.
+func shutdownOnSignal(app *fiber.App, m *Monitor, timeout time.Duration) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-ch
+		signal.Stop(ch)
+		m.signalled.Store(true)
+		defer close(m.stopped)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := app.ShutdownWithContext(ctx); err != nil {
+			log.Printf("[go-monitoring] error shutting down on %v: %v\n", sig, err)
+		}
+
+		// Shutdown is idempotent: if the OnShutdown hook already flushed,
+		// this only confirms the writer is done.
+		fctx, fcancel := context.WithTimeout(context.Background(), timeout)
+		defer fcancel()
+		if err := m.Shutdown(fctx); err != nil {
+			log.Printf("[go-monitoring] error flushing logs on %v: %v\n", sig, err)
+		}
+	}()
+}